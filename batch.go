@@ -0,0 +1,310 @@
+package gss
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+// Batch collects changes to several worksheets of one spreadsheet and
+// sends them with at most one BatchUpdate for the structural requests and
+// Values.BatchUpdate calls for the cell values, each of at most the smallest
+// MaxBatchCells of the worksheets. Removed and appended rows are applied
+// to the worksheets once the first call succeeded, the cell values of each
+// chunk once it was written; a failed Commit can be retried. Batch does not
+// look at ConflictPolicy.
+type Batch struct {
+	ss         *Spreadsheet
+	key        string
+	worksheets []*batchWorksheet
+	sheetOps   []func(map[string]int64, *int) (*sheets.Request, error)
+	// copies is set by SheetCopy, whose tab index needs the current number
+	// of sheets
+	copies bool
+}
+
+type batchWorksheet struct {
+	ws      *Worksheet
+	appends []map[string]Cell
+}
+
+func (ss *Spreadsheet) Batch(key string) *Batch {
+	return &Batch{ss: ss, key: key}
+}
+
+func (b *Batch) worksheet(ws *Worksheet) (*batchWorksheet, error) {
+	if ws.sheetKey != b.key {
+		return nil, fmt.Errorf("%w. worksheet of another spreadsheet. key:%s sheetKey:%s", ErrInvalidArgument, b.key, ws.sheetKey)
+	}
+	if err := ws.rowsLoaded(); err != nil {
+		return nil, err
+	}
+	for _, bw := range b.worksheets {
+		if bw.ws == ws {
+			return bw, nil
+		}
+	}
+	bw := &batchWorksheet{ws: ws}
+	b.worksheets = append(b.worksheets, bw)
+	return bw, nil
+}
+
+// Update sends the changes made to ws.Rows, including removed rows, on
+// Commit.
+func (b *Batch) Update(ws *Worksheet) error {
+	_, err := b.worksheet(ws)
+	return err
+}
+
+// Append adds rows after the last row of ws on Commit.
+func (b *Batch) Append(ws *Worksheet, rows []map[string]string) error {
+	return b.AppendCells(ws, stringCells(rows))
+}
+
+func (b *Batch) AppendCells(ws *Worksheet, rows []map[string]Cell) error {
+	bw, err := b.worksheet(ws)
+	if err != nil {
+		return err
+	}
+	bw.appends = append(bw.appends, rows...)
+	return nil
+}
+
+func (b *Batch) SheetCopy(srcName, dstName string) {
+	b.copies = true
+	b.sheetOps = append(b.sheetOps, func(sheetIdMap map[string]int64, sheetCount *int) (*sheets.Request, error) {
+		sheetId, ok := sheetIdMap[srcName]
+		if !ok {
+			return nil, sheetNotFound(b.key, srcName)
+		}
+		*sheetCount++
+		return &sheets.Request{
+			DuplicateSheet: &sheets.DuplicateSheetRequest{
+				NewSheetName:     dstName,
+				SourceSheetId:    sheetId,
+				InsertSheetIndex: int64(*sheetCount - 1),
+			},
+		}, nil
+	})
+}
+
+func (b *Batch) SheetDelete(name string) {
+	b.sheetOps = append(b.sheetOps, func(sheetIdMap map[string]int64, sheetCount *int) (*sheets.Request, error) {
+		sheetId, ok := sheetIdMap[name]
+		if !ok {
+			return nil, sheetNotFound(b.key, name)
+		}
+		*sheetCount--
+		return &sheets.Request{
+			DeleteSheet: &sheets.DeleteSheetRequest{
+				SheetId: sheetId,
+			},
+		}, nil
+	})
+}
+
+type batchPlan struct {
+	bw      *batchWorksheet
+	deleted []int
+	appends [][]Cell
+}
+
+// batchChunk is one Values.BatchUpdate of a Commit, blocks of several
+// worksheets.
+type batchChunk struct {
+	worksheets []*Worksheet
+	blocks     [][]block
+	cells      int
+}
+
+func (b *Batch) Commit() error {
+	return b.CommitContext(context.Background())
+}
+
+func (b *Batch) CommitContext(ctx context.Context) error {
+	var (
+		plans     = make([]*batchPlan, 0, len(b.worksheets))
+		needIds   = 0 < len(b.sheetOps)
+		requests  = []*sheets.Request{}
+		inputOpts = map[string]bool{}
+	)
+	for _, bw := range b.worksheets {
+		rowIndexes, err := bw.ws.rowIndexes()
+		if err != nil {
+			return err
+		}
+		p := &batchPlan{
+			bw:      bw,
+			deleted: bw.ws.removedRows(rowIndexes),
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(p.deleted)))
+		if (0 < len(p.deleted) || 0 < len(bw.appends)) && !bw.ws.hasSheetId {
+			needIds = true
+		}
+		inputOpts[bw.ws.ValueInputOption] = true
+		plans = append(plans, p)
+	}
+	if 1 < len(inputOpts) {
+		return fmt.Errorf("%w. worksheets must share ValueInputOption. key:%s", ErrInvalidArgument, b.key)
+	}
+
+	var (
+		sheetIdMap map[string]int64
+		err        error
+	)
+	if b.copies {
+		sheetIdMap, err = b.ss.freshSheetIdMap(ctx, b.key)
+	} else if needIds {
+		sheetIdMap, err = b.ss.sheetIdMap(ctx, b.key)
+	}
+	if err != nil {
+		return err
+	}
+	for _, p := range plans {
+		ws := p.bw.ws
+		sheetId := ws.sheetId
+		if !ws.hasSheetId && (0 < len(p.deleted) || 0 < len(p.bw.appends)) {
+			id, ok := sheetIdMap[ws.sheetName]
+			if !ok {
+				return sheetNotFound(b.key, ws.sheetName)
+			}
+			sheetId = id
+		}
+		if 0 < len(p.deleted) {
+			requests = append(requests, ws.deleteRequests(sheetId, p.deleted)...)
+		}
+		if 0 < len(p.bw.appends) {
+			pos := len(ws.values) - len(p.deleted)
+			requests = append(requests, ws.insertRequest(sheetId, pos, len(p.bw.appends)))
+			_, p.appends = ws.rowValues(p.bw.appends)
+		}
+	}
+	sheetCount := len(sheetIdMap)
+	for _, op := range b.sheetOps {
+		req, err := op(sheetIdMap, &sheetCount)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, req)
+	}
+
+	if 0 < len(requests) {
+		var r *sheets.BatchUpdateSpreadsheetResponse
+		err := b.ss.caller.writeOnce(ctx, func() (err error) {
+			r, err = b.ss.service.Spreadsheets.BatchUpdate(b.key, &sheets.BatchUpdateSpreadsheetRequest{
+				Requests: requests,
+			}).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			b.ss.sheetIds.invalidate(b.key, err)
+			return err
+		}
+		names := make(map[int64]string, len(sheetIdMap))
+		for name, id := range sheetIdMap {
+			names[id] = name
+		}
+		b.ss.sheetIds.apply(b.key, requests, r, names)
+	}
+	// the structural changes are on the sheet now, keep the snapshots in
+	// line with it even if writing the values fails. Appended rows go into
+	// the snapshot blank, so their values are changes a retry sends again.
+	for _, p := range plans {
+		ws := p.bw.ws
+		if id, ok := sheetIdMap[ws.sheetName]; ok && !ws.hasSheetId {
+			ws.sheetId = id
+			ws.hasSheetId = true
+		}
+		ws.removeRows(p.deleted)
+		ws.insertStagedRows(len(ws.Rows), len(ws.values), p.appends)
+		p.bw.appends = nil
+	}
+	b.sheetOps = nil
+	b.copies = false
+
+	chunks, err := b.valueChunks()
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data := []*sheets.ValueRange{}
+		for k, ws := range chunk.worksheets {
+			for _, blk := range chunk.blocks[k] {
+				data = append(data, ws.valueRange(blk))
+			}
+		}
+		err := b.ss.caller.write(ctx, func() error {
+			_, err := b.ss.service.Spreadsheets.Values.BatchUpdate(b.key, &sheets.BatchUpdateValuesRequest{
+				Data:             data,
+				ValueInputOption: plans[0].bw.ws.ValueInputOption,
+			}).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for k, ws := range chunk.worksheets {
+			for _, blk := range chunk.blocks[k] {
+				for _, t := range blk.changes {
+					ws.values[t.row][t.col] = t.cell
+				}
+			}
+		}
+	}
+
+	for _, p := range plans {
+		p.bw.ws.adoptRows()
+	}
+	b.worksheets = nil
+	return nil
+}
+
+// valueChunks splits the changes of the worksheets, which are in line with
+// the sheet apart from the values, into chunks of at most the smallest
+// MaxBatchCells of them. Chunks of one worksheet are split like Update does
+// and then packed together with those of the others.
+func (b *Batch) valueChunks() ([]*batchChunk, error) {
+	max := 0
+	for _, bw := range b.worksheets {
+		if n := bw.ws.MaxBatchCells; 0 < n && (max <= 0 || n < max) {
+			max = n
+		}
+	}
+	var (
+		res   = []*batchChunk{}
+		chunk = &batchChunk{}
+	)
+	for _, bw := range b.worksheets {
+		ws := bw.ws
+		rowIndexes, err := ws.rowIndexes()
+		if err != nil {
+			return nil, err
+		}
+		changes := ws.changes(rowIndexes)
+		if len(changes) <= 0 {
+			continue
+		}
+		for _, blocks := range chunkBlocks(coalesce(changes), max) {
+			cells := 0
+			for _, blk := range blocks {
+				cells += blk.cells()
+			}
+			if 0 < max && 0 < chunk.cells && max < chunk.cells+cells {
+				res = append(res, chunk)
+				chunk = &batchChunk{}
+			}
+			chunk.worksheets = append(chunk.worksheets, ws)
+			chunk.blocks = append(chunk.blocks, blocks)
+			chunk.cells += cells
+		}
+	}
+	if 0 < len(chunk.worksheets) {
+		res = append(res, chunk)
+	}
+	return res, nil
+}
@@ -0,0 +1,287 @@
+package gss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchCommit(t *testing.T) {
+	values := map[string]interface{}{
+		"range":          "'シート1'!A1:E4",
+		"majorDimension": "ROWS",
+		"values": []interface{}{
+			[]interface{}{"", "column1", "", "column2", "column3"},
+			[]interface{}{"", "1", "", "4", "7"},
+			[]interface{}{"", "2", "", "5", "8"},
+			[]interface{}{"", "3", "", "6", "9"},
+		},
+	}
+	client, m := newDummyClient(
+		values,
+		values,
+		map[string]interface{}{
+			"sheets": []map[string]interface{}{
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 1,
+						"title":   "シート1",
+					},
+				},
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 2,
+						"title":   "シート2",
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"replies":       []interface{}{},
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws1, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Error(err)
+	}
+	ws2, err := ss.GetWorksheet("XXXXXX", "シート2")
+	if err != nil {
+		t.Error(err)
+	}
+
+	b := ss.Batch("XXXXXX")
+	ws1.Rows[0]["column1"] = "99"
+	assert.NoError(t, b.Update(ws1))
+	ws2.Rows = ws2.Rows[1:]
+	assert.NoError(t, b.Append(ws2, []map[string]string{
+		map[string]string{"column1": "10"},
+	}))
+	b.SheetCopy("シート1", "_シート1")
+	err = b.Commit()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 5, len(m.req))
+
+	var reqData interface{}
+	err = json.NewDecoder(m.req[3].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{
+				"deleteDimension": map[string]interface{}{
+					"range": map[string]interface{}{
+						"sheetId":    2.0,
+						"dimension":  "ROWS",
+						"startIndex": 1.0,
+						"endIndex":   2.0,
+					},
+				},
+			},
+			map[string]interface{}{
+				"insertDimension": map[string]interface{}{
+					"range": map[string]interface{}{
+						"sheetId":    2.0,
+						"dimension":  "ROWS",
+						"startIndex": 3.0,
+						"endIndex":   4.0,
+					},
+					"inheritFromBefore": true,
+				},
+			},
+			map[string]interface{}{
+				"duplicateSheet": map[string]interface{}{
+					"insertSheetIndex": 2.0,
+					"newSheetName":     "_シート1",
+					"sourceSheetId":    1.0,
+				},
+			},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s:batchUpdate", "XXXXXX"), m.req[3].URL.Path)
+
+	err = json.NewDecoder(m.req[4].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData = map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!B2:B2",
+				"values": []interface{}{
+					[]interface{}{"99"},
+				},
+			},
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート2!B4:B4",
+				"values": []interface{}{
+					[]interface{}{"10"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s/values:batchUpdate", "XXXXXX"), m.req[4].URL.Path)
+
+	assert.Equal(t, [][]string{
+		[]string{"", "99", "", "4", "7"},
+		[]string{"", "2", "", "5", "8"},
+		[]string{"", "3", "", "6", "9"},
+	}, ws1.Values())
+	assert.Equal(t, [][]string{
+		[]string{"", "2", "", "5", "8"},
+		[]string{"", "3", "", "6", "9"},
+		[]string{"", "10", "", "", ""},
+	}, ws2.Values())
+	assert.Equal(t, 3, len(ws2.Rows))
+	assert.Equal(t, "10", ws2.Rows[2]["column1"])
+}
+
+func TestBatchCommit_OtherSpreadsheet(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, _ := newDummyClient()
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Error(t, ss.Batch("YYYYYY").Update(ws))
+}
+
+func TestBatchCommit_ValuesFailed(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"3", "c"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &failTransport{s: s, code: http.StatusInternalServerError}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := ss.Batch("XXXXXX")
+	ws.Rows = ws.Rows[1:]
+	ws.Rows[1]["v"] = "C"
+	assert.NoError(t, b.Update(ws))
+	assert.NoError(t, b.Append(ws, []map[string]string{{"id": "4", "v": "d"}}))
+	tr.match = func(req *http.Request) bool {
+		return strings.HasSuffix(req.URL.Path, "values:batchUpdate")
+	}
+	assert.Error(t, b.Commit())
+
+	// the removed and appended rows are kept, the values are sent again
+	tr.match = nil
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "2", "v": "b"},
+		map[string]string{"id": "3", "v": "C"},
+		map[string]string{"id": "4", "v": "d"},
+	}, ws.Rows)
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"3", "C"},
+		[]interface{}{4.0, "d"},
+	}, vals)
+}
+
+func TestBatchCommit_MaxBatchCells(t *testing.T) {
+	s := gsstest.NewServer()
+	for _, name := range []string{"シート1", "シート2"} {
+		_, err := s.AddSheet("XXXXXX", name, [][]interface{}{
+			[]interface{}{"id", "v"},
+			[]interface{}{"1", "a"},
+			[]interface{}{"2", "b"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr := &failTransport{s: s, code: http.StatusBadRequest}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws1, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws2, err := ss.GetWorksheet("XXXXXX", "シート2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws2.MaxBatchCells = 2
+
+	b := ss.Batch("XXXXXX")
+	ws1.Rows[0]["v"] = "A"
+	ws2.Rows[0]["v"] = "A"
+	ws2.Rows[1]["id"] = "22"
+	ws2.Rows[1]["v"] = "B"
+	assert.NoError(t, b.Update(ws1))
+	assert.NoError(t, b.Update(ws2))
+	calls := 0
+	tr.match = func(req *http.Request) bool {
+		if !strings.HasSuffix(req.URL.Path, "values:batchUpdate") {
+			return false
+		}
+		calls++
+		return calls == 2
+	}
+	assert.Error(t, b.Commit())
+	// the first chunk, ws1 and the first row of ws2, was written
+	assert.Equal(t, []string{"1", "A"}, ws1.Values()[0])
+	assert.Equal(t, []string{"1", "A"}, ws2.Values()[0])
+	assert.Equal(t, []string{"2", "b"}, ws2.Values()[1])
+
+	calls = 0
+	assert.NoError(t, b.Commit())
+	vals, err := s.Values("XXXXXX", "シート2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"1", "A"},
+		[]interface{}{22.0, "B"},
+	}, vals)
+	// only the rest of ws2 is sent again, in one chunk
+	assert.Equal(t, 1, calls)
+}
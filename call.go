@@ -0,0 +1,40 @@
+package gss
+
+import (
+	"context"
+)
+
+// caller runs API calls through the retry policy and the limiter a
+// Spreadsheet shares with its Worksheets.
+type caller struct {
+	retry   *RetryPolicy
+	limiter Limiter
+}
+
+func (c caller) run(ctx context.Context, kind CallKind, fn func(attempt int) error, retryable func(error) bool) error {
+	err := c.retry.run(ctx, func(attempt int) error {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx, kind); err != nil {
+				return err
+			}
+		}
+		return fn(attempt)
+	}, retryable)
+	return apiError(err)
+}
+
+func (c caller) read(ctx context.Context, fn func() error) error {
+	return c.run(ctx, ReadCall, func(int) error { return fn() }, c.retry.retryable)
+}
+
+// write runs an idempotent write.
+func (c caller) write(ctx context.Context, fn func() error) error {
+	return c.run(ctx, WriteCall, func(int) error { return fn() }, c.retry.retryable)
+}
+
+// writeOnce runs a write that must not be applied twice.
+func (c caller) writeOnce(ctx context.Context, fn func() error) error {
+	return c.run(ctx, WriteCall, func(int) error { return fn() }, func(err error) bool {
+		return c.retry.retryable(err) && rateLimited(err)
+	})
+}
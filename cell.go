@@ -0,0 +1,189 @@
+package gss
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type CellKind int
+
+const (
+	CellEmpty CellKind = iota
+	CellString
+	CellNumber
+	CellBool
+	CellFormula
+)
+
+// Cell is a typed cell value. Text holds the value of string and formula
+// cells, formulas include the leading "=".
+type Cell struct {
+	Kind   CellKind
+	Text   string
+	Number float64
+	Bool   bool
+}
+
+func StringCell(s string) Cell {
+	return Cell{Kind: CellString, Text: s}
+}
+
+func NumberCell(f float64) Cell {
+	return Cell{Kind: CellNumber, Number: f}
+}
+
+func BoolCell(b bool) Cell {
+	return Cell{Kind: CellBool, Bool: b}
+}
+
+func FormulaCell(f string) Cell {
+	if !strings.HasPrefix(f, "=") {
+		f = "=" + f
+	}
+	return Cell{Kind: CellFormula, Text: f}
+}
+
+// newCell converts a value returned by the API. Strings starting with "="
+// are only formulas when they were read with the FORMULA render option.
+func newCell(v interface{}, formula bool) Cell {
+	switch t := v.(type) {
+	case nil:
+		return Cell{}
+	case string:
+		if t == "" {
+			return Cell{}
+		}
+		if formula && strings.HasPrefix(t, "=") {
+			return Cell{Kind: CellFormula, Text: t}
+		}
+		return StringCell(t)
+	case float64:
+		return NumberCell(t)
+	case bool:
+		return BoolCell(t)
+	}
+	return StringCell(fmt.Sprint(v))
+}
+
+// String returns the text of the cell as it appears in Worksheet.Rows.
+func (c Cell) String() string {
+	switch c.Kind {
+	case CellString, CellFormula:
+		return c.Text
+	case CellNumber:
+		return strconv.FormatFloat(c.Number, 'f', -1, 64)
+	case CellBool:
+		if c.Bool {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return ""
+}
+
+// value returns the cell as sent to the API.
+func (c Cell) value() interface{} {
+	switch c.Kind {
+	case CellNumber:
+		return c.Number
+	case CellBool:
+		return c.Bool
+	}
+	return c.String()
+}
+
+func (ws *Worksheet) newCells(vals []interface{}, cols int) []Cell {
+	var (
+		res     = make([]Cell, cols)
+		formula = ws.ValueRenderOption == "FORMULA"
+	)
+	for i, v := range vals {
+		if i == cols {
+			break
+		}
+		res[i] = newCell(v, formula)
+	}
+	return res
+}
+
+func (ws *Worksheet) Cells() [][]Cell {
+	cells := make([][]Cell, len(ws.values))
+	for i, v := range ws.values {
+		cells[i] = make([]Cell, len(v))
+		copy(cells[i], v)
+	}
+	return cells
+}
+
+type cellKey struct {
+	row    uintptr
+	header string
+}
+
+func (ws *Worksheet) cellKey(row int, header string) (cellKey, error) {
+	if row < 0 || len(ws.Rows) <= row {
+		return cellKey{}, rowOutOfRange(row, len(ws.Rows))
+	}
+	if _, ok := ws.headerIndex(header); !ok {
+		return cellKey{}, headerNotFound(header)
+	}
+	return cellKey{
+		row:    reflect.ValueOf(ws.Rows[row]).Pointer(),
+		header: header,
+	}, nil
+}
+
+// GetCell returns the current value of ws.Rows[row][header], typed when it
+// is unchanged or was set with SetCell.
+func (ws *Worksheet) GetCell(row int, header string) (Cell, error) {
+	key, err := ws.cellKey(row, header)
+	if err != nil {
+		return Cell{}, err
+	}
+	s := ws.Rows[row][header]
+	if c, ok := ws.pending[key]; ok && c.String() == s {
+		return c, nil
+	}
+	if rowIndexes, err := ws.rowIndexes(); err == nil {
+		c, _ := ws.headerIndex(header)
+		if cell := ws.values[rowIndexes[row]][c]; cell.String() == s {
+			return cell, nil
+		}
+	}
+	if s == "" {
+		return Cell{}, nil
+	}
+	return StringCell(s), nil
+}
+
+// SetCell sets ws.Rows[row][header] so that Update sends c with its type
+// instead of as text.
+func (ws *Worksheet) SetCell(row int, header string, c Cell) error {
+	key, err := ws.cellKey(row, header)
+	if err != nil {
+		return err
+	}
+	if ws.pending == nil {
+		ws.pending = map[cellKey]Cell{}
+	}
+	ws.pending[key] = c
+	ws.Rows[row][header] = c.String()
+	return nil
+}
+
+// editedCell returns the cell to send for the text s in row at header.
+func (ws *Worksheet) editedCell(row map[string]string, header, s string) Cell {
+	key := cellKey{
+		row:    reflect.ValueOf(row).Pointer(),
+		header: header,
+	}
+	if c, ok := ws.pending[key]; ok && c.String() == s {
+		return c
+	}
+	if s == "" {
+		return Cell{}
+	}
+	return StringCell(s)
+}
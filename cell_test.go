@@ -0,0 +1,180 @@
+package gss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellString(t *testing.T) {
+	assert.Equal(t, "", Cell{}.String())
+	assert.Equal(t, "abc", StringCell("abc").String())
+	assert.Equal(t, "1.5", NumberCell(1.5).String())
+	assert.Equal(t, "3", NumberCell(3).String())
+	assert.Equal(t, "TRUE", BoolCell(true).String())
+	assert.Equal(t, "=A1+B1", FormulaCell("A1+B1").String())
+	assert.Equal(t, "=A1+B1", FormulaCell("=A1+B1").String())
+	assert.Equal(t, 1.5, NumberCell(1.5).value())
+	assert.Equal(t, false, BoolCell(false).value())
+	assert.Equal(t, "", Cell{}.value())
+}
+
+func TestGetWorksheet_TypedValues(t *testing.T) {
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"range":          "'シート1'!A1:D3",
+			"majorDimension": "ROWS",
+			"values": []interface{}{
+				[]interface{}{"name", "price", "sold", "total"},
+				[]interface{}{"a", 1.5, true, "=B2*2"},
+				[]interface{}{"b", 2, false},
+			},
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", func(ws *Worksheet) {
+		ws.ValueRenderOption = "FORMULA"
+		ws.DateTimeRenderOption = "SERIAL_NUMBER"
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s/values/%s", "XXXXXX", "シート1"), m.req[0].URL.Path)
+	assert.Equal(t, url.Values{
+		"alt":                  []string{"json"},
+		"valueRenderOption":    []string{"FORMULA"},
+		"dateTimeRenderOption": []string{"SERIAL_NUMBER"},
+	}, m.req[0].URL.Query())
+	assert.Equal(t, [][]Cell{
+		[]Cell{StringCell("a"), NumberCell(1.5), BoolCell(true), FormulaCell("B2*2")},
+		[]Cell{StringCell("b"), NumberCell(2), BoolCell(false), Cell{}},
+	}, ws.Cells())
+	assert.Equal(t, []map[string]string{
+		map[string]string{
+			"name":  "a",
+			"price": "1.5",
+			"sold":  "TRUE",
+			"total": "=B2*2",
+		},
+		map[string]string{
+			"name":  "b",
+			"price": "2",
+			"sold":  "FALSE",
+			"total": "",
+		},
+	}, ws.Rows)
+
+	c, err := ws.GetCell(0, "price")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, NumberCell(1.5), c)
+	err = ws.SetCell(1, "price", NumberCell(2.25))
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.SetCell(1, "total", FormulaCell("B3*2"))
+	if err != nil {
+		t.Error(err)
+	}
+	ws.Rows[0]["name"] = "c"
+	c, err = ws.GetCell(0, "name")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, StringCell("c"), c)
+	assert.Error(t, ws.SetCell(5, "price", NumberCell(1)))
+	assert.Error(t, ws.SetCell(0, "unknown", NumberCell(1)))
+
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!A2:A2",
+				"values": []interface{}{
+					[]interface{}{"c"},
+				},
+			},
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!B3:B3",
+				"values": []interface{}{
+					[]interface{}{2.25},
+				},
+			},
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!D3:D3",
+				"values": []interface{}{
+					[]interface{}{"=B3*2"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, NumberCell(2.25), ws.Cells()[1][1])
+	assert.Equal(t, FormulaCell("B3*2"), ws.Cells()[1][3])
+}
+
+func TestWorksheetAppendCells(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.AppendCells([]map[string]Cell{
+		map[string]Cell{
+			"column1": NumberCell(10),
+			"column2": BoolCell(true),
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData interface{}
+	err = json.NewDecoder(m.req[0].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"majorDimension": "ROWS",
+		"values": []interface{}{
+			[]interface{}{nil, 10.0, nil, true, ""},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, map[string]string{
+		"column1": "10",
+		"column2": "TRUE",
+		"column3": "",
+	}, ws.Rows[3])
+}
@@ -0,0 +1,335 @@
+package gss
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tagName = "gss"
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type field struct {
+	name   string
+	index  []int
+	layout string
+}
+
+// fields returns the columns a struct type maps onto. Columns are named by
+// the `gss:"name"` tag, or by the field name when untagged. `gss:"-"` skips
+// the field and `gss:"name,layout=2006-01-02"` sets the time.Time layout.
+func fields(t reflect.Type) []field {
+	res := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		if f.Anonymous && opts[0] == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				if f.PkgPath != "" {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				for _, sf := range fields(ft) {
+					sf.index = append([]int{i}, sf.index...)
+					res = append(res, sf)
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		fd := field{
+			name:   opts[0],
+			index:  []int{i},
+			layout: time.RFC3339,
+		}
+		if fd.name == "" {
+			fd.name = f.Name
+		}
+		for _, opt := range opts[1:] {
+			if strings.HasPrefix(opt, "layout=") {
+				fd.layout = strings.TrimPrefix(opt, "layout=")
+			}
+		}
+		res = append(res, fd)
+	}
+	return res
+}
+
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func structType(v reflect.Value) (reflect.Value, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, fmt.Errorf("nil pointer. type:%s", v.Type())
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return v, fmt.Errorf("not a struct. type:%s", v.Type())
+	}
+	return v, nil
+}
+
+// UnmarshalRow stores the values of row into the struct pointed to by v.
+func UnmarshalRow(row map[string]string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("non-pointer passed to UnmarshalRow. type:%T", v)
+	}
+	sv, err := structType(rv)
+	if err != nil {
+		return err
+	}
+	return unmarshalRow(row, sv)
+}
+
+func unmarshalRow(row map[string]string, sv reflect.Value) error {
+	for _, f := range fields(sv.Type()) {
+		s, ok := row[f.name]
+		if !ok {
+			continue
+		}
+		fv, _ := fieldByIndex(sv, f.index, true)
+		if err := decodeValue(s, fv, f.layout); err != nil {
+			return fmt.Errorf("column:%s %s", f.name, err)
+		}
+	}
+	return nil
+}
+
+// MarshalRow converts the struct v into a row keyed by column name.
+func MarshalRow(v interface{}) (map[string]string, error) {
+	sv, err := structType(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return marshalRow(sv, nil)
+}
+
+// marshalRow encodes sv. Cells of prev whose text decodes to the same value
+// as the field are kept verbatim, so that "1.50" is not rewritten as "1.5".
+func marshalRow(sv reflect.Value, prev map[string]string) (map[string]string, error) {
+	fs := fields(sv.Type())
+	row := make(map[string]string, len(fs))
+	for _, f := range fs {
+		fv, ok := fieldByIndex(sv, f.index, false)
+		if !ok {
+			row[f.name] = ""
+			continue
+		}
+		s, err := encodeValue(fv, f.layout)
+		if err != nil {
+			return nil, fmt.Errorf("column:%s %s", f.name, err)
+		}
+		if p, ok := prev[f.name]; ok && p != s {
+			pv := reflect.New(fv.Type()).Elem()
+			if decodeValue(p, pv, f.layout) == nil && reflect.DeepEqual(pv.Interface(), fv.Interface()) {
+				s = p
+			}
+		}
+		row[f.name] = s
+	}
+	return row, nil
+}
+
+func decodeValue(s string, v reflect.Value, layout string) error {
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(s, v.Elem(), layout)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == timeType {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if s == "" && v.Kind() != reflect.String {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.Replace(s, ",", "", -1), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.Replace(s, ",", "", -1), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.Replace(s, ",", "", -1), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type. type:%s", v.Type())
+	}
+	return nil
+}
+
+func encodeValue(v reflect.Value, layout string) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		return encodeValue(v.Elem(), layout)
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type. type:%s", v.Type())
+}
+
+// Decode stores ws.Rows into v, which must be a pointer to a slice of
+// structs (or of struct pointers).
+func (ws *Worksheet) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("decode needs a pointer to a slice. type:%T", v)
+	}
+	var (
+		slice = rv.Elem()
+		et    = slice.Type().Elem()
+		res   = reflect.MakeSlice(slice.Type(), len(ws.Rows), len(ws.Rows))
+	)
+	for i, row := range ws.Rows {
+		ev := res.Index(i)
+		if et.Kind() == reflect.Ptr {
+			ev.Set(reflect.New(et.Elem()))
+		}
+		sv, err := structType(ev)
+		if err != nil {
+			return err
+		}
+		if err := unmarshalRow(row, sv); err != nil {
+			return fmt.Errorf("row:%d %s", i, err)
+		}
+	}
+	slice.Set(res)
+	return nil
+}
+
+// Encode writes the structs of v over ws.Rows. Fields whose column is not
+// on the sheet are skipped. Nothing is sent until Update is called, and only
+// cells whose value actually changed are sent then.
+func (ws *Worksheet) Encode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("encode needs a slice. type:%T", v)
+	}
+	if len(ws.Rows) < rv.Len() {
+		return fmt.Errorf("%w, too many rows, use Append for new rows. rows:%d len:%d", ErrUnknownRow, len(ws.Rows), rv.Len())
+	}
+	rows := make([]map[string]string, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		sv, err := structType(rv.Index(i))
+		if err != nil {
+			return fmt.Errorf("row:%d %s", i, err)
+		}
+		row, err := marshalRow(sv, ws.Rows[i])
+		if err != nil {
+			return fmt.Errorf("row:%d %s", i, err)
+		}
+		// fields without a column are skipped, as Decode does
+		for k := range row {
+			if _, ok := ws.headerIndex(k); !ok {
+				delete(row, k)
+			}
+		}
+		rows[i] = row
+	}
+	for i, row := range rows {
+		for k, s := range row {
+			ws.Rows[i][k] = s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package gss
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dummyRow struct {
+	Column1 int      `gss:"column1"`
+	Column2 *float64 `gss:"column2"`
+	Column3 string   `gss:"column3"`
+	Ignored string   `gss:"-"`
+}
+
+func TestWorksheetDecode(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	ws.Rows[1]["column2"] = ""
+	var rows []dummyRow
+	err = ws.Decode(&rows)
+	if err != nil {
+		t.Error(err)
+	}
+	four, six := 4.0, 6.0
+	assert.Equal(t, []dummyRow{
+		dummyRow{Column1: 1, Column2: &four, Column3: "7"},
+		dummyRow{Column1: 2, Column2: nil, Column3: "8"},
+		dummyRow{Column1: 3, Column2: &six, Column3: "9"},
+	}, rows)
+
+	var ptrs []*dummyRow
+	err = ws.Decode(&ptrs)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 3, len(ptrs))
+	assert.Equal(t, 3, ptrs[2].Column1)
+
+	ws.Rows[0]["column1"] = "x"
+	err = ws.Decode(&rows)
+	assert.Error(t, err)
+}
+
+func TestWorksheetEncode(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	var rows []dummyRow
+	err = ws.Decode(&rows)
+	if err != nil {
+		t.Error(err)
+	}
+	rows[0].Column1 = 99
+	rows[1].Column2 = nil
+	err = ws.Encode(rows)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, []map[string]string{
+		map[string]string{
+			"column1": "99",
+			"column2": "4",
+			"column3": "7",
+		},
+		map[string]string{
+			"column1": "2",
+			"column2": "",
+			"column3": "8",
+		},
+		map[string]string{
+			"column1": "3",
+			"column2": "6",
+			"column3": "9",
+		},
+	}, ws.Rows)
+
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData map[string]interface{}
+	err = json.NewDecoder(m.req[0].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 2, len(reqData["data"].([]interface{})))
+
+	err = ws.Encode(append(rows, dummyRow{}))
+	assert.Error(t, err)
+}
+
+func TestWorksheetEncode_UnknownColumn(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []struct {
+		ID   int `gss:"column1"`
+		Note string
+	}
+	if err := ws.Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, rows[0].ID)
+	rows[0].ID = 10
+	rows[0].Note = "x"
+	if err := ws.Encode(rows); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{
+		"column1": "10",
+		"column2": "4",
+		"column3": "7",
+	}, ws.Rows[0])
+}
+
+func TestMarshalRow(t *testing.T) {
+	type embedded struct {
+		Note string `gss:"note"`
+	}
+	type row struct {
+		embedded
+		Flag    bool      `gss:"flag"`
+		Date    time.Time `gss:"date,layout=2006-01-02"`
+		Count   uint8
+		private string
+	}
+	r, err := MarshalRow(row{
+		embedded: embedded{Note: "n"},
+		Flag:     true,
+		Date:     time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC),
+		Count:    3,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, map[string]string{
+		"note":  "n",
+		"flag":  "TRUE",
+		"date":  "2017-04-01",
+		"Count": "3",
+	}, r)
+
+	var u row
+	err = UnmarshalRow(r, &u)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "n", u.Note)
+	assert.Equal(t, true, u.Flag)
+	assert.Equal(t, time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC), u.Date)
+	assert.Equal(t, uint8(3), u.Count)
+}
+
+func TestMarshalRow_KeepEquivalent(t *testing.T) {
+	v := 1.5
+	sv, err := structType(reflect.ValueOf(&dummyRow{Column1: 1, Column2: &v}))
+	if err != nil {
+		t.Error(err)
+	}
+	r, err := marshalRow(sv, map[string]string{
+		"column1": "1",
+		"column2": "1.50",
+		"column3": "",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "1.50", r["column2"])
+}
@@ -0,0 +1,152 @@
+package gss
+
+import (
+	"context"
+	"fmt"
+)
+
+// ConflictPolicy decides what Update does with cells that were changed on
+// the sheet since the last Refresh.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite writes local changes without looking at the sheet.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictFail writes nothing and returns a *ConflictError.
+	ConflictFail
+	// ConflictMerge writes the cells that were not changed on the sheet,
+	// takes in remote changes of cells not edited locally, and returns a
+	// *ConflictError for the rest. Conflicting cells take the remote value
+	// in ws.Rows too; set Conflict.Local again to overwrite it.
+	ConflictMerge
+)
+
+type Conflict struct {
+	Row    int
+	Header string
+	Base   string
+	Local  string
+	Remote string
+}
+
+type ConflictError struct {
+	SheetKey  string
+	SheetName string
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict. key:%s sheetName:%s cells:%d", e.SheetKey, e.SheetName, len(e.Conflicts))
+}
+
+// resolveConflicts re-reads the rows touched by changes and compares them
+// against the snapshot. Consecutive changed rows are read as one range, all
+// ranges with one BatchGet.
+func (ws *Worksheet) resolveConflicts(ctx context.Context, rowIndexes []int, changes []change) ([]change, error) {
+	if len(changes) <= 0 {
+		return changes, nil
+	}
+	var (
+		firsts = []int{}
+		lasts  = []int{}
+		ranges = []string{}
+	)
+	for _, c := range changes {
+		if n := len(lasts); 0 < n && c.index <= lasts[n-1]+1 {
+			lasts[n-1] = c.index
+			continue
+		}
+		firsts = append(firsts, c.index)
+		lasts = append(lasts, c.index)
+	}
+	for k, first := range firsts {
+		ranges = append(ranges, fmt.Sprintf(
+			"%s!A%d:%s%d",
+			ws.sheetName, first+ws.dataRow(), n2c(ws.cols), lasts[k]+ws.dataRow(),
+		))
+	}
+	vrs, err := ws.batchGetValues(ctx, ranges)
+	if err != nil {
+		return nil, err
+	}
+	remote := make(map[int][]Cell, len(changes))
+	for k, first := range firsts {
+		for i := first; i <= lasts[k]; i++ {
+			var vals []interface{}
+			if i-first < len(vrs[k].Values) {
+				vals = vrs[k].Values[i-first]
+			}
+			remote[i] = ws.newCells(vals, ws.cols)
+		}
+	}
+
+	var (
+		res       = make([]change, 0, len(changes))
+		conflicts = []Conflict{}
+		touched   = make(map[[2]int]bool, len(changes))
+	)
+	for _, c := range changes {
+		touched[[2]int{c.index, c.col}] = true
+		var (
+			base = ws.values[c.index][c.col].String()
+			rc   = remote[c.index][c.col]
+			rv   = rc.String()
+		)
+		if rv == base || rv == c.val {
+			res = append(res, c)
+			continue
+		}
+		conflicts = append(conflicts, Conflict{
+			Row:    c.row,
+			Header: ws.header(c.col),
+			Base:   base,
+			Local:  c.val,
+			Remote: rv,
+		})
+		if ws.ConflictPolicy == ConflictMerge {
+			// the remote value wins, the local one is only kept in the
+			// Conflict so that a later Update does not overwrite it silently
+			ws.values[c.index][c.col] = rc
+			ws.Rows[c.row][ws.header(c.col)] = rv
+		} else {
+			res = append(res, c)
+		}
+	}
+
+	if ws.ConflictPolicy == ConflictMerge {
+		for r, i := range rowIndexes {
+			cells, ok := remote[i]
+			if !ok {
+				continue
+			}
+			for j, k := range ws.headers {
+				c := ws.headerIndexes[j]
+				if touched[[2]int{i, c}] {
+					continue
+				}
+				if rc := cells[c]; rc.String() != ws.values[i][c].String() {
+					ws.values[i][c] = rc
+					ws.Rows[r][k] = rc.String()
+				}
+			}
+		}
+	}
+
+	if 0 < len(conflicts) {
+		return res, &ConflictError{
+			SheetKey:  ws.sheetKey,
+			SheetName: ws.sheetName,
+			Conflicts: conflicts,
+		}
+	}
+	return res, nil
+}
+
+func (ws *Worksheet) header(col int) string {
+	for j, c := range ws.headerIndexes {
+		if c == col {
+			return ws.headers[j]
+		}
+	}
+	return ""
+}
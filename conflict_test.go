@@ -0,0 +1,257 @@
+package gss
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorksheetUpdate_ConflictFail(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+			"valueRanges": []interface{}{
+				map[string]interface{}{
+					"range":          "'シート1'!A2:E3",
+					"majorDimension": "ROWS",
+					"values": []interface{}{
+						[]interface{}{"", "1", "", "4", "7"},
+						[]interface{}{"", "2", "", "55", "8"},
+					},
+				},
+			},
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.ConflictPolicy = ConflictFail
+	ws.Rows[0]["column1"] = "99"
+	ws.Rows[1]["column2"] = "99"
+	err = ws.Update()
+	assert.Equal(t, &ConflictError{
+		SheetKey:  "XXXXXX",
+		SheetName: "シート1",
+		Conflicts: []Conflict{
+			Conflict{
+				Row:    1,
+				Header: "column2",
+				Base:   "5",
+				Local:  "99",
+				Remote: "55",
+			},
+		},
+	}, err)
+	assert.Equal(t, 1, len(m.req))
+	assert.Equal(t, "/v4/spreadsheets/XXXXXX/values:batchGet", m.req[0].URL.Path)
+	assert.Equal(t, []string{fmt.Sprintf("%s!A2:E3", "シート1")}, m.req[0].URL.Query()["ranges"])
+	assert.Equal(t, [][]string{
+		[]string{"", "1", "", "4", "7"},
+		[]string{"", "2", "", "5", "8"},
+		[]string{"", "3", "", "6", "9"},
+	}, ws.Values())
+}
+
+func TestWorksheetUpdate_ConflictMerge(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+			"valueRanges": []interface{}{
+				map[string]interface{}{
+					"range":          "'シート1'!A2:E3",
+					"majorDimension": "ROWS",
+					"values": []interface{}{
+						[]interface{}{"", "1", "", "4", "77"},
+						[]interface{}{"", "2", "", "55", "8"},
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.ConflictPolicy = ConflictMerge
+	ws.Rows[0]["column1"] = "99"
+	ws.Rows[1]["column2"] = "99"
+	err = ws.Update()
+	conflictErr, ok := err.(*ConflictError)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(conflictErr.Conflicts))
+
+	var reqData interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!B2:B2",
+				"values": []interface{}{
+					[]interface{}{"99"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, [][]string{
+		[]string{"", "99", "", "4", "77"},
+		[]string{"", "2", "", "55", "8"},
+		[]string{"", "3", "", "6", "9"},
+	}, ws.Values())
+	assert.Equal(t, []map[string]string{
+		map[string]string{
+			"column1": "99",
+			"column2": "4",
+			"column3": "77",
+		},
+		map[string]string{
+			"column1": "2",
+			"column2": "55",
+			"column3": "8",
+		},
+		map[string]string{
+			"column1": "3",
+			"column2": "6",
+			"column3": "9",
+		},
+	}, ws.Rows)
+}
+
+func TestWorksheetUpdate_ConflictMergeTwice(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"1", "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.Rows[0]["v"] = "remote"
+	if err := remote.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	ws.ConflictPolicy = ConflictMerge
+	ws.Rows[0]["v"] = "local"
+	err = ws.Update()
+	var conflictErr *ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []Conflict{
+		Conflict{Row: 0, Header: "v", Base: "a", Local: "local", Remote: "remote"},
+	}, conflictErr.Conflicts)
+	assert.Equal(t, "remote", ws.Rows[0]["v"])
+
+	// nothing is left to send, the remote edit stays
+	assert.NoError(t, ws.Update())
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{"1", "remote"}, vals[1])
+
+	// setting the local value again is a plain edit
+	ws.Rows[0]["v"] = conflictErr.Conflicts[0].Local
+	assert.NoError(t, ws.Update())
+	vals, err = s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{"1", "local"}, vals[1])
+}
+
+// rangesTransport records the ranges of values:batchGet requests.
+type rangesTransport struct {
+	http.RoundTripper
+	ranges [][]string
+}
+
+func (t *rangesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "values:batchGet") {
+		t.ranges = append(t.ranges, req.URL.Query()["ranges"])
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func TestWorksheetUpdate_ConflictRanges(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"3", "c"},
+		[]interface{}{"4", "d"},
+		[]interface{}{"5", "e"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &rangesTransport{RoundTripper: s}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.Rows[1]["id"] = "22"
+	remote.Rows[2]["id"] = "33"
+	if err := remote.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr.ranges = nil
+	ws.ConflictPolicy = ConflictMerge
+	ws.Rows[0]["v"] = "A"
+	ws.Rows[1]["v"] = "B"
+	ws.Rows[4]["v"] = "E"
+	assert.NoError(t, ws.Update())
+	// only the changed rows are read, so only row 1 takes in the remote edit
+	assert.Equal(t, [][]string{
+		[]string{"シート1!A2:B3", "シート1!A6:B6"},
+	}, tr.ranges)
+	assert.Equal(t, "22", ws.Rows[1]["id"])
+	assert.Equal(t, "3", ws.Rows[2]["id"])
+}
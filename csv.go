@@ -0,0 +1,94 @@
+package gss
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCSV writes the headers and ws.Rows, including uncommitted edits, as
+// CSV.
+func (ws *Worksheet) WriteCSV(w io.Writer) error {
+	var (
+		cw      = csv.NewWriter(w)
+		headers = ws.Headers()
+		record  = make([]string, len(headers))
+	)
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+	for _, row := range ws.Rows {
+		for i, h := range headers {
+			record[i] = row[h]
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (ws *Worksheet) ImportCSV(r io.Reader, mode ImportMode) (*ImportResult, error) {
+	return ws.ImportCSVContext(context.Background(), r, mode)
+}
+
+// ImportCSVContext reads a CSV whose first record holds the headers. The
+// rows are sent with Update and Append in chunks of at most MaxBatchCells
+// cells.
+func (ws *Worksheet) ImportCSVContext(ctx context.Context, r io.Reader, mode ImportMode) (*ImportResult, error) {
+	rows, missing, err := ws.readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return ws.importRows(ctx, rows, missing, mode)
+}
+
+func (ws *Worksheet) readCSV(r io.Reader) ([]map[string]string, []string, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w. csv is empty", ErrNoHeader)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if 0 < len(header) {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	seen := make(map[string]bool, len(header))
+	for j, h := range header {
+		if seen[h] {
+			return nil, nil, &DuplicateHeaderError{
+				SheetKey:  ws.sheetKey,
+				SheetName: ws.sheetName,
+				Header:    h,
+				Columns:   []string{n2c(j + 1)},
+			}
+		}
+		seen[h] = true
+	}
+	missing, err := ws.checkColumns(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := []map[string]string{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, h := range header {
+			row[h] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, missing, nil
+}
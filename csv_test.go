@@ -0,0 +1,112 @@
+package gss
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCSVWorksheet(t *testing.T) (*gsstest.Server, *Worksheet) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "name", "note"},
+		[]interface{}{"1", "a", "x"},
+		[]interface{}{"2", "b", "y"},
+		[]interface{}{"3", "c", "z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, ws
+}
+
+func TestWorksheetWriteCSV(t *testing.T) {
+	_, ws := newCSVWorksheet(t)
+	ws.Rows[1]["note"] = "a,b"
+	var buf bytes.Buffer
+	if err := ws.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "id,name,note\n1,a,x\n2,b,\"a,b\"\n3,c,z\n", buf.String())
+}
+
+func TestWorksheetImportCSV(t *testing.T) {
+	s, ws := newCSVWorksheet(t)
+
+	_, err := ws.ImportCSV(strings.NewReader("id,unknown\n1,a\n"), ImportAppend)
+	var unknown *UnknownColumnsError
+	assert.True(t, errors.As(err, &unknown))
+	assert.Equal(t, []string{"unknown"}, unknown.Columns)
+	_, err = ws.ImportCSV(strings.NewReader("id,id\n1,2\n"), ImportAppend)
+	var dup *DuplicateHeaderError
+	assert.True(t, errors.As(err, &dup))
+	_, err = ws.ImportCSV(strings.NewReader(""), ImportAppend)
+	assert.True(t, errors.Is(err, ErrNoHeader))
+	_, err = ws.ImportCSV(strings.NewReader("id\n9\n"), ImportUpsert)
+	assert.True(t, errors.Is(err, ErrNoKey))
+
+	// the BOM Excel writes is ignored, a chunk of five cells holds one row
+	ws.MaxBatchCells = 5
+	res, err := ws.ImportCSV(strings.NewReader("\ufeffname,id\nd,4\ne,5\n"), ImportAppend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ImportResult{Missing: []string{"note"}, Appended: 2}, res)
+
+	if err := ws.SetKey("id"); err != nil {
+		t.Fatal(err)
+	}
+	res, err = ws.ImportCSV(strings.NewReader("id,name\n2,B\n6,f\n"), ImportUpsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ImportResult{Missing: []string{"note"}, Updated: 1, Appended: 1}, res)
+	_, err = ws.ImportCSV(strings.NewReader("id\n7\n7\n"), ImportUpsert)
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name", "note"},
+		[]interface{}{"1", "a", "x"},
+		[]interface{}{"2", "B", "y"},
+		[]interface{}{"3", "c", "z"},
+		[]interface{}{4.0, "d"},
+		[]interface{}{5.0, "e"},
+		[]interface{}{6.0, "f"},
+	}, vals)
+
+	res, err = ws.ImportCSV(strings.NewReader("id,name,note\n1,a,\n8,h,w\n"), ImportReplace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ImportResult{Missing: []string{}, Updated: 2, Deleted: 4}, res)
+	vals, err = s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name", "note"},
+		[]interface{}{"1", "a"},
+		[]interface{}{8.0, "h", "w"},
+	}, vals)
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "1", "name": "a", "note": ""},
+		map[string]string{"id": "8", "name": "h", "note": "w"},
+	}, ws.Rows)
+}
@@ -0,0 +1,115 @@
+package gss
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Errors returned by this package wrap one of these, test them with
+// errors.Is. *MissingHeaderError and *UnknownColumnsError match
+// ErrHeaderNotFound, *DuplicateHeaderError matches ErrDuplicateHeader.
+var (
+	ErrSheetNotFound  = errors.New("sheet_id not found")
+	ErrNoHeader       = errors.New("no header")
+	ErrHeaderNotFound = errors.New("header not found")
+	ErrRowOutOfRange  = errors.New("row index out of range")
+	ErrNoKey          = errors.New("no key")
+	ErrDuplicateKey   = errors.New("duplicate key")
+	ErrRowsNotLoaded  = errors.New("rows not loaded")
+
+	ErrDuplicateHeader = errors.New("duplicate header")
+	ErrNoKeyValue      = errors.New("no key value")
+	ErrUnknownRow      = errors.New("unknown row")
+	ErrRowsReordered   = errors.New("rows must not be reordered")
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// An *APIError matches these with errors.Is depending on its status.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+)
+
+var quotaReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"quotaExceeded":         true,
+	"dailyLimitExceeded":    true,
+}
+
+// APIError is an error response of the Sheets API. Err is the original
+// *googleapi.Error.
+type APIError struct {
+	Code    int
+	Reason  string
+	Message string
+	Quota   bool
+	Err     *googleapi.Error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == http.StatusNotFound
+	case ErrPermissionDenied:
+		return !e.Quota && (e.Code == http.StatusUnauthorized || e.Code == http.StatusForbidden)
+	case ErrQuotaExceeded:
+		return e.Quota
+	}
+	return false
+}
+
+// apiError wraps a *googleapi.Error found in err into an *APIError and
+// returns other errors as is.
+func apiError(err error) error {
+	var e *APIError
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	var gErr *googleapi.Error
+	if !errors.As(err, &gErr) {
+		return err
+	}
+	res := &APIError{
+		Code:    gErr.Code,
+		Message: gErr.Message,
+		Quota:   gErr.Code == http.StatusTooManyRequests,
+		Err:     gErr,
+	}
+	if 0 < len(gErr.Errors) {
+		res.Reason = gErr.Errors[0].Reason
+		if res.Message == "" {
+			res.Message = gErr.Errors[0].Message
+		}
+	}
+	for _, item := range gErr.Errors {
+		if quotaReasons[item.Reason] {
+			res.Quota = true
+		}
+	}
+	return res
+}
+
+func sheetNotFound(key, name string) error {
+	return fmt.Errorf("%w. key:%s name:%s", ErrSheetNotFound, key, name)
+}
+
+func headerNotFound(header string) error {
+	return fmt.Errorf("%w. header:%s", ErrHeaderNotFound, header)
+}
+
+func rowOutOfRange(row, rows int) error {
+	return fmt.Errorf("%w. row:%d rows:%d", ErrRowOutOfRange, row, rows)
+}
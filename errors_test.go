@@ -0,0 +1,112 @@
+package gss
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	err := apiError(fmt.Errorf("wrapped: %w", &googleapi.Error{
+		Code:    http.StatusForbidden,
+		Message: "Rate Limit Exceeded",
+		Errors: []googleapi.ErrorItem{
+			googleapi.ErrorItem{Reason: "rateLimitExceeded", Message: "Rate Limit Exceeded"},
+		},
+	}))
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusForbidden, apiErr.Code)
+		assert.Equal(t, "rateLimitExceeded", apiErr.Reason)
+		assert.Equal(t, "Rate Limit Exceeded", apiErr.Message)
+		assert.True(t, apiErr.Quota)
+	}
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.False(t, errors.Is(err, ErrPermissionDenied))
+	var gErr *googleapi.Error
+	assert.True(t, errors.As(err, &gErr))
+	assert.Equal(t, err, apiError(err))
+
+	err = apiError(&googleapi.Error{Code: http.StatusForbidden})
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+	assert.False(t, errors.Is(err, ErrQuotaExceeded))
+	assert.True(t, errors.Is(apiError(&googleapi.Error{Code: http.StatusNotFound}), ErrNotFound))
+	assert.True(t, errors.Is(apiError(&googleapi.Error{Code: http.StatusTooManyRequests}), ErrQuotaExceeded))
+
+	plain := errors.New("plain")
+	assert.Equal(t, plain, apiError(plain))
+	assert.Nil(t, apiError(nil))
+}
+
+func TestErrors_Sentinels(t *testing.T) {
+	client, _ := newDummyClient(
+		dummyErrorResponse{StatusCode: http.StatusNotFound},
+		map[string]interface{}{
+			"range":          "'シート1'!A1:A1",
+			"majorDimension": "ROWS",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+			"sheets": []interface{}{
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 1,
+						"title":   "シート1",
+					},
+				},
+			},
+		},
+	)
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
+	}
+
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.True(t, errors.Is(err, ErrNoHeader))
+	assert.Equal(t, "no header. key:XXXXXX sheetName:シート1", err.Error())
+
+	err = ss.SheetDelete("XXXXXX", "unknown")
+	assert.True(t, errors.Is(err, ErrSheetNotFound))
+	assert.Equal(t, "sheet_id not found. key:XXXXXX name:unknown", err.Error())
+
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.True(t, errors.Is(ws.SetKey("unknown"), ErrHeaderNotFound))
+	assert.True(t, errors.Is(ws.SetCell(5, "column1", Cell{}), ErrRowOutOfRange))
+	_, _, err = ws.FindByKey("1")
+	assert.True(t, errors.Is(err, ErrNoKey))
+
+	err = ws.RequireHeaders("column1", "unknown")
+	var missing *MissingHeaderError
+	assert.True(t, errors.As(err, &missing))
+	assert.True(t, errors.Is(err, ErrHeaderNotFound))
+	assert.True(t, errors.Is(&UnknownColumnsError{Columns: []string{"a"}}, ErrHeaderNotFound))
+	assert.True(t, errors.Is(&DuplicateHeaderError{Header: "a"}, ErrDuplicateHeader))
+	assert.False(t, errors.Is(&DuplicateHeaderError{Header: "a"}, ErrHeaderNotFound))
+
+	if err := ws.SetKey("column1"); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, errors.Is(ws.Upsert([]map[string]string{{"column2": "x"}}), ErrNoKeyValue))
+	ws.Rows = append(ws.Rows, map[string]string{})
+	_, err = ws.rowIndexes()
+	assert.True(t, errors.Is(err, ErrUnknownRow))
+	ws.Rows = []map[string]string{ws.Rows[1], ws.Rows[0]}
+	_, err = ws.rowIndexes()
+	assert.True(t, errors.Is(err, ErrRowsReordered))
+	assert.True(t, errors.Is(ws.Iterate(0, nil), ErrInvalidArgument))
+}
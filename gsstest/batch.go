@@ -0,0 +1,299 @@
+package gsstest
+
+import (
+	"fmt"
+	"strings"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+// batchUpdate applies the requests to a copy of ss and only keeps it when
+// all of them succeeded, like the real API.
+func (s *Server) batchUpdate(ss *spreadsheet, req *sheets.BatchUpdateSpreadsheetRequest) (*sheets.BatchUpdateSpreadsheetResponse, error) {
+	var (
+		tmp    = ss.clone()
+		nextId = s.nextSheetId
+		res    = &sheets.BatchUpdateSpreadsheetResponse{SpreadsheetId: ss.key}
+	)
+	newSheetId := func(id int64) (int64, error) {
+		if id == 0 {
+			id = nextId
+			nextId++
+		}
+		if _, sh := tmp.sheetById(id); sh != nil {
+			return 0, badRequest("Invalid sheetId: %d already exists", id)
+		}
+		return id, nil
+	}
+	for i, r := range req.Requests {
+		reply, err := tmp.apply(r, newSheetId)
+		if err != nil {
+			if e, ok := err.(*apiError); ok {
+				e.message = fmt.Sprintf("Invalid requests[%d]: %s", i, e.message)
+			}
+			return nil, err
+		}
+		res.Replies = append(res.Replies, reply)
+	}
+	s.spreadsheets[ss.key] = tmp
+	s.nextSheetId = nextId
+	return res, nil
+}
+
+func (ss *spreadsheet) apply(r *sheets.Request, newSheetId func(int64) (int64, error)) (*sheets.Response, error) {
+	switch {
+	case r.AddSheet != nil:
+		props := r.AddSheet.Properties
+		if props == nil {
+			props = &sheets.SheetProperties{}
+		}
+		title := props.Title
+		if title == "" {
+			title = fmt.Sprintf("Sheet%d", len(ss.sheets)+1)
+		}
+		sh := &sheet{
+			title:    title,
+			rows:     defaultRowCount,
+			cols:     defaultColumnCount,
+			hidden:   props.Hidden,
+			tabColor: props.TabColor,
+		}
+		if g := props.GridProperties; g != nil {
+			if 0 < g.RowCount {
+				sh.rows = int(g.RowCount)
+			}
+			if 0 < g.ColumnCount {
+				sh.cols = int(g.ColumnCount)
+			}
+			sh.frozenRows = int(g.FrozenRowCount)
+			sh.frozenCols = int(g.FrozenColumnCount)
+		}
+		// Index is -1 when it was left out
+		index := len(ss.sheets)
+		if 0 <= props.Index && int(props.Index) < index {
+			index = int(props.Index)
+		}
+		if err := ss.insertSheet(sh, props.SheetId, index, newSheetId); err != nil {
+			return nil, err
+		}
+		return &sheets.Response{
+			AddSheet: &sheets.AddSheetResponse{Properties: sh.properties(index)},
+		}, nil
+
+	case r.DuplicateSheet != nil:
+		d := r.DuplicateSheet
+		_, src := ss.sheetById(d.SourceSheetId)
+		if src == nil {
+			return nil, badRequest("No grid with id: %d", d.SourceSheetId)
+		}
+		sh := src.clone()
+		sh.title = d.NewSheetName
+		if sh.title == "" {
+			sh.title = "Copy of " + src.title
+		}
+		index := int(d.InsertSheetIndex)
+		if len(ss.sheets) < index {
+			index = len(ss.sheets)
+		}
+		if err := ss.insertSheet(sh, d.NewSheetId, index, newSheetId); err != nil {
+			return nil, err
+		}
+		return &sheets.Response{
+			DuplicateSheet: &sheets.DuplicateSheetResponse{Properties: sh.properties(index)},
+		}, nil
+
+	case r.DeleteSheet != nil:
+		i, sh := ss.sheetById(r.DeleteSheet.SheetId)
+		if sh == nil {
+			return nil, badRequest("No grid with id: %d", r.DeleteSheet.SheetId)
+		}
+		if len(ss.sheets) <= 1 {
+			return nil, badRequest("You can't remove all the sheets in a document.")
+		}
+		ss.sheets = append(ss.sheets[:i], ss.sheets[i+1:]...)
+		return &sheets.Response{}, nil
+
+	case r.UpdateSheetProperties != nil:
+		u := r.UpdateSheetProperties
+		if u.Properties == nil {
+			return nil, badRequest("properties is required")
+		}
+		i, sh := ss.sheetById(u.Properties.SheetId)
+		if sh == nil {
+			return nil, badRequest("No grid with id: %d", u.Properties.SheetId)
+		}
+		if err := ss.updateProperties(i, u.Properties, u.Fields); err != nil {
+			return nil, err
+		}
+		return &sheets.Response{}, nil
+
+	case r.InsertDimension != nil:
+		sh, start, end, err := ss.dimensionRange(r.InsertDimension.Range)
+		if err != nil {
+			return nil, err
+		}
+		if r.InsertDimension.Range.Dimension == "COLUMNS" {
+			if sh.cols < start {
+				return nil, badRequest("Cannot insert columns beyond the grid. columns:%d", sh.cols)
+			}
+			sh.insertCols(start, end-start)
+		} else {
+			if sh.rows < start {
+				return nil, badRequest("Cannot insert rows beyond the grid. rows:%d", sh.rows)
+			}
+			sh.insertRows(start, end-start)
+		}
+		return &sheets.Response{}, nil
+
+	case r.DeleteDimension != nil:
+		sh, start, end, err := ss.dimensionRange(r.DeleteDimension.Range)
+		if err != nil {
+			return nil, err
+		}
+		if r.DeleteDimension.Range.Dimension == "COLUMNS" {
+			if sh.cols < end {
+				return nil, badRequest("Cannot delete columns beyond the grid. columns:%d", sh.cols)
+			}
+			sh.deleteCols(start, end)
+			sh.cols -= end - start
+		} else {
+			if sh.rows < end {
+				return nil, badRequest("Cannot delete rows beyond the grid. rows:%d", sh.rows)
+			}
+			sh.deleteRows(start, end)
+			sh.rows -= end - start
+		}
+		return &sheets.Response{}, nil
+	}
+	return nil, badRequest("unsupported request")
+}
+
+var sheetFields = map[string]bool{
+	"sheetId":                          true,
+	"title":                            true,
+	"index":                            true,
+	"hidden":                           true,
+	"tabColor":                         true,
+	"gridProperties":                   true,
+	"gridProperties.rowCount":          true,
+	"gridProperties.columnCount":       true,
+	"gridProperties.frozenRowCount":    true,
+	"gridProperties.frozenColumnCount": true,
+}
+
+// updateProperties sets the fields of p, a comma separated list or "*",
+// on ss.sheets[i].
+func (ss *spreadsheet) updateProperties(i int, p *sheets.SheetProperties, fields string) error {
+	var (
+		sh   = ss.sheets[i]
+		grid = p.GridProperties
+	)
+	if grid == nil {
+		grid = &sheets.GridProperties{}
+	}
+	all := fields == "*"
+	if fields == "" {
+		return badRequest("fields is required")
+	}
+	for _, f := range strings.Split(fields, ",") {
+		if f = strings.TrimSpace(f); f != "*" && !sheetFields[f] {
+			return badRequest("Invalid field: %s", f)
+		}
+	}
+	has := func(f string) bool {
+		if all {
+			return true
+		}
+		for _, g := range strings.Split(fields, ",") {
+			g = strings.TrimSpace(g)
+			if g == f || strings.HasPrefix(f, g+".") {
+				return true
+			}
+		}
+		return false
+	}
+	if has("title") && p.Title != sh.title {
+		if p.Title == "" {
+			return badRequest("title must not be empty")
+		}
+		if ss.sheet(p.Title) != nil {
+			return badRequest("A sheet with the name \"%s\" already exists. Please enter another name.", p.Title)
+		}
+		sh.title = p.Title
+	}
+	if has("hidden") {
+		if p.Hidden {
+			visible := 0
+			for _, t := range ss.sheets {
+				if !t.hidden {
+					visible++
+				}
+			}
+			if !sh.hidden && visible <= 1 {
+				return badRequest("You can't hide all the sheets in a document.")
+			}
+		}
+		sh.hidden = p.Hidden
+	}
+	if has("tabColor") {
+		sh.tabColor = p.TabColor
+	}
+	if has("gridProperties.rowCount") && 0 < grid.RowCount {
+		sh.rows = int(grid.RowCount)
+	}
+	if has("gridProperties.columnCount") && 0 < grid.ColumnCount {
+		sh.cols = int(grid.ColumnCount)
+	}
+	if has("gridProperties.frozenRowCount") {
+		sh.frozenRows = int(grid.FrozenRowCount)
+	}
+	if has("gridProperties.frozenColumnCount") {
+		sh.frozenCols = int(grid.FrozenColumnCount)
+	}
+	if has("index") {
+		// index counts positions before the sheet is taken out
+		index := int(p.Index)
+		if len(ss.sheets) < index {
+			index = len(ss.sheets)
+		}
+		if i < index {
+			index--
+		}
+		ss.sheets = append(ss.sheets[:i], ss.sheets[i+1:]...)
+		ss.sheets = append(ss.sheets[:index], append([]*sheet{sh}, ss.sheets[index:]...)...)
+	}
+	return nil
+}
+
+func (ss *spreadsheet) insertSheet(sh *sheet, id int64, index int, newSheetId func(int64) (int64, error)) error {
+	if ss.sheet(sh.title) != nil {
+		return badRequest("A sheet with the name \"%s\" already exists. Please enter another name.", sh.title)
+	}
+	id, err := newSheetId(id)
+	if err != nil {
+		return err
+	}
+	sh.id = id
+	ss.sheets = append(ss.sheets[:index], append([]*sheet{sh}, ss.sheets[index:]...)...)
+	return nil
+}
+
+func (ss *spreadsheet) dimensionRange(r *sheets.DimensionRange) (*sheet, int, int, error) {
+	if r == nil {
+		return nil, 0, 0, badRequest("range is required")
+	}
+	_, sh := ss.sheetById(r.SheetId)
+	if sh == nil {
+		return nil, 0, 0, badRequest("No grid with id: %d", r.SheetId)
+	}
+	switch r.Dimension {
+	case "ROWS", "COLUMNS":
+	default:
+		return nil, 0, 0, badRequest("Invalid dimension: %s", r.Dimension)
+	}
+	start, end := int(r.StartIndex), int(r.EndIndex)
+	if start < 0 || end <= start {
+		return nil, 0, 0, badRequest("Invalid dimension range. start:%d end:%d", start, end)
+	}
+	return sh, start, end, nil
+}
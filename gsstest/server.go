@@ -0,0 +1,632 @@
+// Package gsstest provides an in-memory Sheets API backend for testing code
+// built on gss without network access.
+package gsstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+const (
+	defaultRowCount    = 1000
+	defaultColumnCount = 26
+)
+
+// Server is a stateful fake of the Sheets API v4. It implements reading and
+// writing values, creating spreadsheets, copying sheets between them and
+// the structural requests gss sends: adding, duplicating, deleting and
+// updating the properties of sheets and inserting and deleting rows or
+// columns.
+//
+// Values written with USER_ENTERED are parsed into numbers and booleans like
+// the real API does. Formulas are stored but not evaluated, they read back
+// as their text with every render option.
+//
+// Use Client to pass it to gss.NewSpreadsheet, or serve it with
+// httptest.NewServer.
+type Server struct {
+	mu           sync.Mutex
+	spreadsheets map[string]*spreadsheet
+	nextSheetId  int64
+	failures     []int
+}
+
+type spreadsheet struct {
+	key      string
+	title    string
+	locale   string
+	timeZone string
+	sheets   []*sheet
+}
+
+type sheet struct {
+	id         int64
+	title      string
+	rows       int
+	cols       int
+	frozenRows int
+	frozenCols int
+	hidden     bool
+	tabColor   *sheets.Color
+	values     [][]interface{}
+}
+
+func NewServer() *Server {
+	return &Server{spreadsheets: map[string]*spreadsheet{}}
+}
+
+// Client returns a client whose requests are served by s.
+func (s *Server) Client() *http.Client {
+	return &http.Client{Transport: s}
+}
+
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	res := w.Result()
+	res.Request = req
+	return res, nil
+}
+
+// AddSpreadsheet creates an empty spreadsheet, it is a no-op when key
+// already exists.
+func (s *Server) AddSpreadsheet(key, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addSpreadsheet(key, title)
+}
+
+func (s *Server) addSpreadsheet(key, title string) *spreadsheet {
+	if ss, ok := s.spreadsheets[key]; ok {
+		return ss
+	}
+	ss := &spreadsheet{
+		key:      key,
+		title:    title,
+		locale:   "en_US",
+		timeZone: "Etc/GMT",
+	}
+	s.spreadsheets[key] = ss
+	return ss
+}
+
+// AddSheet adds a sheet holding values to the spreadsheet key, creating the
+// spreadsheet if needed. values are stored as given, without parsing.
+func (s *Server) AddSheet(key, title string, values [][]interface{}) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss := s.addSpreadsheet(key, key)
+	if ss.sheet(title) != nil {
+		return 0, fmt.Errorf("sheet already exists. key:%s name:%s", key, title)
+	}
+	sh := &sheet{
+		id:    s.newSheetId(),
+		title: title,
+		rows:  defaultRowCount,
+		cols:  defaultColumnCount,
+	}
+	for r, row := range values {
+		for c, v := range row {
+			sh.set(r, c, v)
+		}
+	}
+	ss.sheets = append(ss.sheets, sh)
+	return sh.id, nil
+}
+
+// Values returns the values stored in a sheet without trailing empty rows
+// and cells.
+func (s *Server) Values(key, title string) ([][]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss, ok := s.spreadsheets[key]
+	if !ok {
+		return nil, fmt.Errorf("spreadsheet not found. key:%s", key)
+	}
+	sh := ss.sheet(title)
+	if sh == nil {
+		return nil, fmt.Errorf("sheet not found. key:%s name:%s", key, title)
+	}
+	return sh.read(0, 0, sh.rows, sh.cols, "UNFORMATTED_VALUE"), nil
+}
+
+// SheetTitles returns the titles of the sheets of key in order.
+func (s *Server) SheetTitles(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss, ok := s.spreadsheets[key]
+	if !ok {
+		return nil
+	}
+	res := make([]string, len(ss.sheets))
+	for i, sh := range ss.sheets {
+		res[i] = sh.title
+	}
+	return res
+}
+
+// FailNext makes the next request fail with the status code, without
+// touching any state. Calls queue up.
+func (s *Server) FailNext(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, code)
+}
+
+func (s *Server) newSheetId() int64 {
+	id := s.nextSheetId
+	s.nextSheetId++
+	return id
+}
+
+func (ss *spreadsheet) sheet(title string) *sheet {
+	for _, sh := range ss.sheets {
+		if sh.title == title {
+			return sh
+		}
+	}
+	return nil
+}
+
+func (ss *spreadsheet) sheetById(id int64) (int, *sheet) {
+	for i, sh := range ss.sheets {
+		if sh.id == id {
+			return i, sh
+		}
+	}
+	return -1, nil
+}
+
+func (ss *spreadsheet) clone() *spreadsheet {
+	res := *ss
+	res.sheets = make([]*sheet, len(ss.sheets))
+	for i, sh := range ss.sheets {
+		res.sheets[i] = sh.clone()
+	}
+	return &res
+}
+
+func (sh *sheet) clone() *sheet {
+	res := *sh
+	res.values = make([][]interface{}, len(sh.values))
+	for i, row := range sh.values {
+		res.values[i] = append([]interface{}{}, row...)
+	}
+	return &res
+}
+
+func (sh *sheet) set(r, c int, v interface{}) {
+	for len(sh.values) <= r {
+		sh.values = append(sh.values, nil)
+	}
+	for len(sh.values[r]) <= c {
+		sh.values[r] = append(sh.values[r], nil)
+	}
+	sh.values[r][c] = v
+	if sh.rows <= r {
+		sh.rows = r + 1
+	}
+	if sh.cols <= c {
+		sh.cols = c + 1
+	}
+}
+
+// read returns rows [top, bottom) and cols [left, right) without trailing
+// empty rows and cells.
+func (sh *sheet) read(top, left, bottom, right int, render string) [][]interface{} {
+	res := [][]interface{}{}
+	for r := top; r < bottom && r < len(sh.values); r++ {
+		row := []interface{}{}
+		for c := left; c < right && c < len(sh.values[r]); c++ {
+			row = append(row, render1(sh.values[r][c], render))
+		}
+		for 0 < len(row) && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		res = append(res, row)
+	}
+	for 0 < len(res) && len(res[len(res)-1]) == 0 {
+		res = res[:len(res)-1]
+	}
+	return res
+}
+
+// lastRow returns the index of the last row holding a value, or -1.
+func (sh *sheet) lastRow() int {
+	for r := len(sh.values) - 1; 0 <= r; r-- {
+		for _, v := range sh.values[r] {
+			if v != nil {
+				return r
+			}
+		}
+	}
+	return -1
+}
+
+func (sh *sheet) insertRows(start, n int) {
+	if start < len(sh.values) {
+		sh.values = append(sh.values[:start], append(make([][]interface{}, n), sh.values[start:]...)...)
+	}
+	sh.rows += n
+}
+
+func (sh *sheet) deleteRows(start, end int) {
+	if start < len(sh.values) {
+		if len(sh.values) < end {
+			end = len(sh.values)
+		}
+		sh.values = append(sh.values[:start], sh.values[end:]...)
+	}
+}
+
+func (sh *sheet) insertCols(start, n int) {
+	for i, row := range sh.values {
+		if start < len(row) {
+			sh.values[i] = append(row[:start], append(make([]interface{}, n), row[start:]...)...)
+		}
+	}
+	sh.cols += n
+}
+
+func (sh *sheet) deleteCols(start, end int) {
+	for i, row := range sh.values {
+		if start < len(row) {
+			e := end
+			if len(row) < e {
+				e = len(row)
+			}
+			sh.values[i] = append(row[:start], row[e:]...)
+		}
+	}
+}
+
+// render1 converts a stored value as the API returns it.
+func render1(v interface{}, render string) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		if render == "UNFORMATTED_VALUE" || render == "FORMULA" {
+			return t
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		if render == "UNFORMATTED_VALUE" || render == "FORMULA" {
+			return t
+		}
+		if t {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return v
+}
+
+// parse converts a value sent by a client as the sheet stores it.
+func parse(v interface{}, input string) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if s == "" {
+		return nil
+	}
+	if input != "USER_ENTERED" || strings.HasPrefix(s, "=") {
+		return s
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	switch strings.ToUpper(s) {
+	case "TRUE":
+		return true
+	case "FALSE":
+		return false
+	}
+	return s
+}
+
+type apiError struct {
+	code    int
+	status  string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return &apiError{
+		code:    http.StatusBadRequest,
+		status:  "INVALID_ARGUMENT",
+		message: fmt.Sprintf(format, a...),
+	}
+}
+
+func notFound(format string, a ...interface{}) error {
+	return &apiError{
+		code:    http.StatusNotFound,
+		status:  "NOT_FOUND",
+		message: fmt.Sprintf(format, a...),
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = &apiError{
+			code:    http.StatusInternalServerError,
+			status:  "INTERNAL",
+			message: err.Error(),
+		}
+	}
+	reason := "badRequest"
+	switch e.code {
+	case http.StatusNotFound:
+		reason = "notFound"
+	case http.StatusForbidden:
+		reason = "forbidden"
+	case http.StatusTooManyRequests:
+		reason = "rateLimitExceeded"
+	case http.StatusInternalServerError, http.StatusServiceUnavailable:
+		reason = "backendError"
+	}
+	writeJSON(w, e.code, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    e.code,
+			"message": e.message,
+			"status":  e.status,
+			"errors": []interface{}{
+				map[string]interface{}{
+					"reason":  reason,
+					"message": e.message,
+				},
+			},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if 0 < len(s.failures) {
+		code := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, &apiError{
+			code:    code,
+			status:  strings.ToUpper(strings.Replace(http.StatusText(code), " ", "_", -1)),
+			message: http.StatusText(code),
+		})
+		return
+	}
+	res, err := s.serve(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) serve(req *http.Request) (interface{}, error) {
+	path := strings.TrimPrefix(req.URL.EscapedPath(), "/")
+	if !strings.HasPrefix(path, "v4/spreadsheets") {
+		return nil, notFound("unknown path. path:%s", req.URL.Path)
+	}
+	segs := []string{}
+	for _, seg := range strings.Split(strings.TrimPrefix(path, "v4/spreadsheets"), "/")[1:] {
+		v, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, badRequest("invalid path. path:%s", req.URL.Path)
+		}
+		segs = append(segs, v)
+	}
+	if len(segs) <= 0 && req.Method == http.MethodPost {
+		var body sheets.Spreadsheet
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, badRequest("invalid body. %s", err)
+		}
+		return s.create(&body)
+	}
+	if len(segs) <= 0 || segs[0] == "" {
+		return nil, notFound("unknown path. path:%s", req.URL.Path)
+	}
+
+	key := strings.TrimSuffix(segs[0], ":batchUpdate")
+	ss, ok := s.spreadsheets[key]
+	if !ok {
+		return nil, notFound("Requested entity was not found.")
+	}
+	q := req.URL.Query()
+	switch {
+	case len(segs) == 1 && req.Method == http.MethodGet:
+		return ss.properties(), nil
+	case len(segs) == 1 && req.Method == http.MethodPost && strings.HasSuffix(segs[0], ":batchUpdate"):
+		var (
+			body sheets.BatchUpdateSpreadsheetRequest
+			// index is left out of the JSON when it is 0, tell apart whether
+			// it was set
+			indexes struct {
+				Requests []struct {
+					AddSheet *struct {
+						Properties *struct {
+							Index *int64 `json:"index"`
+						} `json:"properties"`
+					} `json:"addSheet"`
+				} `json:"requests"`
+			}
+		)
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			return nil, badRequest("invalid body. %s", err)
+		}
+		if err := json.Unmarshal(b, &indexes); err != nil {
+			return nil, badRequest("invalid body. %s", err)
+		}
+		for i, r := range indexes.Requests {
+			if a := r.AddSheet; a != nil && a.Properties != nil && a.Properties.Index == nil {
+				body.Requests[i].AddSheet.Properties.Index = -1
+			}
+		}
+		return s.batchUpdate(ss, &body)
+	case len(segs) == 3 && req.Method == http.MethodPost && segs[1] == "sheets" && strings.HasSuffix(segs[2], ":copyTo"):
+		sheetId, err := strconv.ParseInt(strings.TrimSuffix(segs[2], ":copyTo"), 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid sheetId. %s", segs[2])
+		}
+		var body sheets.CopySheetToAnotherSpreadsheetRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, badRequest("invalid body. %s", err)
+		}
+		return s.copyTo(ss, sheetId, body.DestinationSpreadsheetId)
+	case len(segs) == 2 && req.Method == http.MethodPost && segs[1] == "values:batchUpdate":
+		var body sheets.BatchUpdateValuesRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, badRequest("invalid body. %s", err)
+		}
+		return ss.batchUpdateValues(&body)
+	case len(segs) == 2 && req.Method == http.MethodGet && segs[1] == "values:batchGet":
+		return ss.batchGetValues(q["ranges"], q.Get("valueRenderOption"))
+	case len(segs) == 3 && segs[1] == "values":
+		var (
+			rng  = segs[2]
+			body sheets.ValueRange
+		)
+		switch {
+		case req.Method == http.MethodGet:
+			return ss.getValues(rng, q.Get("valueRenderOption"))
+		case req.Method == http.MethodPost && strings.HasSuffix(rng, ":append"):
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, badRequest("invalid body. %s", err)
+			}
+			return ss.appendValues(strings.TrimSuffix(rng, ":append"), &body, q.Get("valueInputOption"), q.Get("insertDataOption"))
+		case req.Method == http.MethodPut:
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, badRequest("invalid body. %s", err)
+			}
+			return ss.updateValues(rng, &body, q.Get("valueInputOption"))
+		}
+	}
+	return nil, notFound("unknown path. method:%s path:%s", req.Method, req.URL.Path)
+}
+
+func (s *Server) create(req *sheets.Spreadsheet) (*sheets.Spreadsheet, error) {
+	var key string
+	for i := len(s.spreadsheets) + 1; ; i++ {
+		key = fmt.Sprintf("gsstest-%d", i)
+		if _, ok := s.spreadsheets[key]; !ok {
+			break
+		}
+	}
+	title := "Untitled spreadsheet"
+	if req.Properties != nil && req.Properties.Title != "" {
+		title = req.Properties.Title
+	}
+	ss := &spreadsheet{
+		key:      key,
+		title:    title,
+		locale:   "en_US",
+		timeZone: "Etc/GMT",
+	}
+	reqs := req.Sheets
+	if len(reqs) <= 0 {
+		reqs = []*sheets.Sheet{&sheets.Sheet{}}
+	}
+	for i, r := range reqs {
+		props := r.Properties
+		if props == nil {
+			props = &sheets.SheetProperties{}
+		}
+		props.Index = int64(i)
+		if _, err := ss.apply(&sheets.Request{
+			AddSheet: &sheets.AddSheetRequest{Properties: props},
+		}, func(id int64) (int64, error) {
+			if id == 0 {
+				return s.newSheetId(), nil
+			}
+			return id, nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	s.spreadsheets[key] = ss
+	return ss.properties(), nil
+}
+
+// copyTo copies a sheet to the end of dstKey as "Copy of <title>".
+func (s *Server) copyTo(ss *spreadsheet, sheetId int64, dstKey string) (*sheets.SheetProperties, error) {
+	_, src := ss.sheetById(sheetId)
+	if src == nil {
+		return nil, notFound("Requested entity was not found.")
+	}
+	dst, ok := s.spreadsheets[dstKey]
+	if !ok {
+		return nil, notFound("Requested entity was not found.")
+	}
+	sh := src.clone()
+	sh.id = s.newSheetId()
+	sh.title = "Copy of " + src.title
+	for i := 2; dst.sheet(sh.title) != nil; i++ {
+		sh.title = fmt.Sprintf("Copy of %s %d", src.title, i)
+	}
+	dst.sheets = append(dst.sheets, sh)
+	return sh.properties(len(dst.sheets) - 1), nil
+}
+
+func (ss *spreadsheet) properties() *sheets.Spreadsheet {
+	res := &sheets.Spreadsheet{
+		SpreadsheetId: ss.key,
+		Properties: &sheets.SpreadsheetProperties{
+			Title:    ss.title,
+			Locale:   ss.locale,
+			TimeZone: ss.timeZone,
+		},
+		Sheets: make([]*sheets.Sheet, len(ss.sheets)),
+	}
+	for i, sh := range ss.sheets {
+		res.Sheets[i] = &sheets.Sheet{Properties: sh.properties(i)}
+	}
+	return res
+}
+
+func (sh *sheet) properties(index int) *sheets.SheetProperties {
+	return &sheets.SheetProperties{
+		SheetId:   sh.id,
+		Title:     sh.title,
+		Index:     int64(index),
+		SheetType: "GRID",
+		Hidden:    sh.hidden,
+		TabColor:  sh.tabColor,
+		GridProperties: &sheets.GridProperties{
+			RowCount:          int64(sh.rows),
+			ColumnCount:       int64(sh.cols),
+			FrozenRowCount:    int64(sh.frozenRows),
+			FrozenColumnCount: int64(sh.frozenCols),
+		},
+	}
+}
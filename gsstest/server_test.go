@@ -0,0 +1,202 @@
+package gsstest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	gss "github.com/mix3/go-gss"
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer(t *testing.T) *gsstest.Server {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "name", "price"},
+		[]interface{}{1.0, "apple", 100.0},
+		[]interface{}{2.0, "banana", 200.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestServer_Worksheet(t *testing.T) {
+	s := newServer(t)
+	ss, err := gss.NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "1", "name": "apple", "price": "100"},
+		map[string]string{"id": "2", "name": "banana", "price": "200"},
+	}, ws.Rows)
+
+	ws.Rows[1]["price"] = "250"
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	err = ws.Append([]map[string]string{
+		map[string]string{"id": "3", "name": "cherry", "price": "300"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ws.InsertRows(0, []map[string]string{
+		map[string]string{"id": "0", "name": "apricot"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.DeleteRows(2); err != nil {
+		t.Fatal(err)
+	}
+	values, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name", "price"},
+		[]interface{}{0.0, "apricot"},
+		[]interface{}{1.0, "apple", 100.0},
+		[]interface{}{3.0, "cherry", 300.0},
+	}, values)
+
+	ws2, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ws.Rows, ws2.Rows)
+}
+
+func TestServer_Sheets(t *testing.T) {
+	s := newServer(t)
+	ss, err := gss.NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetCopy("XXXXXX", "シート1", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"シート1", "copy"}, s.SheetTitles("XXXXXX"))
+	assert.Error(t, ss.SheetCopy("XXXXXX", "シート1", "copy"))
+
+	ws, err := ss.GetWorksheet("XXXXXX", "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(ws.Rows))
+
+	if err := ss.SheetDelete("XXXXXX", "シート1"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"copy"}, s.SheetTitles("XXXXXX"))
+	assert.Error(t, ss.SheetDelete("XXXXXX", "copy"))
+
+	_, err = ss.GetWorksheet("YYYYYY", "シート1")
+	assert.True(t, errors.Is(err, gss.ErrNotFound))
+}
+
+func TestServer_Batch(t *testing.T) {
+	s := newServer(t)
+	ss, err := gss.NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := ss.Batch("XXXXXX")
+	ws.Rows[0]["name"] = "avocado"
+	ws.Rows = ws.Rows[:1]
+	if err := b.Update(ws); err != nil {
+		t.Fatal(err)
+	}
+	err = b.Append(ws, []map[string]string{
+		map[string]string{"id": "4", "name": "durian", "price": "400"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SheetCopy("シート1", "backup")
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{
+		[]interface{}{"id", "name", "price"},
+		[]interface{}{1.0, "avocado", 100.0},
+		[]interface{}{4.0, "durian", 400.0},
+	}
+	values, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, want, values)
+	// the copy is made by the structural requests, before values are written
+	values, err = s.Values("XXXXXX", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name", "price"},
+		[]interface{}{1.0, "apple", 100.0},
+	}, values)
+}
+
+func TestServer_FailNext(t *testing.T) {
+	s := newServer(t)
+	ss, err := gss.NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.FailNext(http.StatusTooManyRequests)
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.True(t, errors.Is(err, gss.ErrQuotaExceeded))
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.NoError(t, err)
+}
+
+func TestServer_HTTP(t *testing.T) {
+	s := newServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/v4/spreadsheets/XXXXXX/values/%E3%82%B7%E3%83%BC%E3%83%881!A2:B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestServer_BatchGet(t *testing.T) {
+	s := newServer(t)
+	service, err := sheets.New(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := service.Spreadsheets.Values.BatchGet("XXXXXX").Ranges("シート1!B2:B3", "シート1!A1:A1").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, r.ValueRanges, 2)
+	assert.Equal(t, "'シート1'!B2:B3", r.ValueRanges[0].Range)
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"apple"},
+		[]interface{}{"banana"},
+	}, r.ValueRanges[0].Values)
+	assert.Equal(t, [][]interface{}{[]interface{}{"id"}}, r.ValueRanges[1].Values)
+
+	_, err = service.Spreadsheets.Values.BatchGet("XXXXXX").Ranges("unknown!A1").Do()
+	assert.Error(t, err)
+}
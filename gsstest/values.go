@@ -0,0 +1,274 @@
+package gsstest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+// gridRange is a parsed A1 range, rows and cols are 0-based and bottom and
+// right exclusive.
+type gridRange struct {
+	sheet  *sheet
+	top    int
+	left   int
+	bottom int
+	right  int
+}
+
+func (g gridRange) String() string {
+	return fmt.Sprintf("%s!%s%d:%s%d", quote(g.sheet.title), c2n(g.left), g.top+1, c2n(g.right-1), g.bottom)
+}
+
+func quote(title string) string {
+	return "'" + strings.Replace(title, "'", "''", -1) + "'"
+}
+
+// c2n turns a 0-based column into its letters, c2n(0) is "A".
+func c2n(c int) string {
+	s := ""
+	for c++; 0 < c; c = (c - 1) / 26 {
+		s = string(rune('A'+(c-1)%26)) + s
+	}
+	return s
+}
+
+// parseCell parses "B3" into col 2 and row 3, both 1-based and 0 when left
+// out.
+func parseCell(s string) (int, int, bool) {
+	i := 0
+	col := 0
+	for ; i < len(s) && 'A' <= s[i]&^0x20 && s[i]&^0x20 <= 'Z'; i++ {
+		col = col*26 + int(s[i]&^0x20-'A'+1)
+	}
+	row := 0
+	if i < len(s) {
+		n, err := strconv.Atoi(s[i:])
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		row = n
+	}
+	return col, row, 0 < col || 0 < row
+}
+
+func (ss *spreadsheet) parseRange(rng string) (gridRange, error) {
+	var (
+		title = rng
+		a1    = ""
+	)
+	if i := strings.LastIndex(rng, "!"); 0 <= i {
+		title, a1 = rng[:i], rng[i+1:]
+	}
+	if 2 <= len(title) && strings.HasPrefix(title, "'") && strings.HasSuffix(title, "'") {
+		title = strings.Replace(title[1:len(title)-1], "''", "'", -1)
+	}
+	sh := ss.sheet(title)
+	if sh == nil {
+		return gridRange{}, badRequest("Unable to parse range: %s", rng)
+	}
+	g := gridRange{sheet: sh, bottom: sh.rows, right: sh.cols}
+	if a1 == "" {
+		return g, nil
+	}
+	parts := strings.SplitN(a1, ":", 2)
+	c1, r1, ok := parseCell(parts[0])
+	if !ok {
+		return gridRange{}, badRequest("Unable to parse range: %s", rng)
+	}
+	c2, r2 := c1, r1
+	if len(parts) == 2 {
+		if c2, r2, ok = parseCell(parts[1]); !ok {
+			return gridRange{}, badRequest("Unable to parse range: %s", rng)
+		}
+	} else if r1 == 0 || c1 == 0 {
+		return gridRange{}, badRequest("Unable to parse range: %s", rng)
+	}
+	if 0 < c1 {
+		g.left = c1 - 1
+	}
+	if 0 < r1 {
+		g.top = r1 - 1
+	}
+	if 0 < c2 {
+		g.right = c2
+	}
+	if 0 < r2 {
+		g.bottom = r2
+	}
+	if g.bottom < g.top || g.right < g.left {
+		return gridRange{}, badRequest("Unable to parse range: %s", rng)
+	}
+	return g, nil
+}
+
+func (ss *spreadsheet) getValues(rng, render string) (*sheets.ValueRange, error) {
+	g, err := ss.parseRange(rng)
+	if err != nil {
+		return nil, err
+	}
+	return &sheets.ValueRange{
+		Range:          g.String(),
+		MajorDimension: "ROWS",
+		Values:         g.sheet.read(g.top, g.left, g.bottom, g.right, render),
+	}, nil
+}
+
+func (ss *spreadsheet) batchGetValues(ranges []string, render string) (*sheets.BatchGetValuesResponse, error) {
+	res := &sheets.BatchGetValuesResponse{SpreadsheetId: ss.key}
+	for _, rng := range ranges {
+		vr, err := ss.getValues(rng, render)
+		if err != nil {
+			return nil, err
+		}
+		res.ValueRanges = append(res.ValueRanges, vr)
+	}
+	return res, nil
+}
+
+func checkWrite(vr *sheets.ValueRange, input string) error {
+	switch vr.MajorDimension {
+	case "", "ROWS", "COLUMNS":
+	default:
+		return badRequest("Invalid majorDimension: %s", vr.MajorDimension)
+	}
+	switch input {
+	case "RAW", "USER_ENTERED":
+	case "":
+		return badRequest("'valueInputOption' is required but not specified")
+	default:
+		return badRequest("Invalid valueInputOption: %s", input)
+	}
+	return nil
+}
+
+// write puts values into the sheet from the top left of g. nil values are
+// skipped unless clear is set.
+func (g gridRange) write(vr *sheets.ValueRange, input string, clear bool) (*sheets.UpdateValuesResponse, error) {
+	if err := checkWrite(vr, input); err != nil {
+		return nil, err
+	}
+	values := vr.Values
+	if vr.MajorDimension == "COLUMNS" {
+		values = transpose(values)
+	}
+	width := 0
+	for r, row := range values {
+		if width < len(row) {
+			width = len(row)
+		}
+		for c, v := range row {
+			if v == nil && !clear {
+				continue
+			}
+			g.sheet.set(g.top+r, g.left+c, parse(v, input))
+		}
+	}
+	res := gridRange{
+		sheet:  g.sheet,
+		top:    g.top,
+		left:   g.left,
+		bottom: g.top + len(values),
+		right:  g.left + width,
+	}
+	return &sheets.UpdateValuesResponse{
+		UpdatedRange:   res.String(),
+		UpdatedRows:    int64(len(values)),
+		UpdatedColumns: int64(width),
+		UpdatedCells:   int64(len(values) * width),
+	}, nil
+}
+
+func transpose(values [][]interface{}) [][]interface{} {
+	res := [][]interface{}{}
+	for c, col := range values {
+		for r, v := range col {
+			for len(res) <= r {
+				res = append(res, []interface{}{})
+			}
+			for len(res[r]) <= c {
+				res[r] = append(res[r], nil)
+			}
+			res[r][c] = v
+		}
+	}
+	return res
+}
+
+func (ss *spreadsheet) updateValues(rng string, vr *sheets.ValueRange, input string) (*sheets.UpdateValuesResponse, error) {
+	g, err := ss.parseRange(rng)
+	if err != nil {
+		return nil, err
+	}
+	res, err := g.write(vr, input, false)
+	if err != nil {
+		return nil, err
+	}
+	res.SpreadsheetId = ss.key
+	return res, nil
+}
+
+// appendValues writes below the last row holding a value, or at the top of
+// rng when that is lower.
+func (ss *spreadsheet) appendValues(rng string, vr *sheets.ValueRange, input, insert string) (*sheets.AppendValuesResponse, error) {
+	g, err := ss.parseRange(rng)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWrite(vr, input); err != nil {
+		return nil, err
+	}
+	tableRange := ""
+	if last := g.sheet.lastRow(); g.top <= last {
+		table := gridRange{sheet: g.sheet, top: g.top, left: g.left, bottom: last + 1, right: g.right}
+		tableRange = table.String()
+		g.top = last + 1
+	}
+	if insert == "INSERT_ROWS" {
+		g.sheet.insertRows(g.top, len(vr.Values))
+	}
+	res, err := g.write(vr, input, true)
+	if err != nil {
+		return nil, err
+	}
+	res.SpreadsheetId = ss.key
+	return &sheets.AppendValuesResponse{
+		SpreadsheetId: ss.key,
+		TableRange:    tableRange,
+		Updates:       res,
+	}, nil
+}
+
+func (ss *spreadsheet) batchUpdateValues(req *sheets.BatchUpdateValuesRequest) (*sheets.BatchUpdateValuesResponse, error) {
+	ranges := make([]gridRange, len(req.Data))
+	for i, vr := range req.Data {
+		g, err := ss.parseRange(vr.Range)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkWrite(vr, req.ValueInputOption); err != nil {
+			return nil, err
+		}
+		ranges[i] = g
+	}
+	var (
+		res     = &sheets.BatchUpdateValuesResponse{SpreadsheetId: ss.key}
+		touched = map[*sheet]bool{}
+	)
+	for i, vr := range req.Data {
+		r, err := ranges[i].write(vr, req.ValueInputOption, false)
+		if err != nil {
+			return nil, err
+		}
+		r.SpreadsheetId = ss.key
+		res.Responses = append(res.Responses, r)
+		res.TotalUpdatedCells += r.UpdatedCells
+		res.TotalUpdatedRows += r.UpdatedRows
+		res.TotalUpdatedColumns += r.UpdatedColumns
+		touched[ranges[i].sheet] = true
+	}
+	res.TotalUpdatedSheets = int64(len(touched))
+	return res, nil
+}
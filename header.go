@@ -0,0 +1,229 @@
+package gss
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DuplicateHeaderError struct {
+	SheetKey  string
+	SheetName string
+	Header    string
+	Columns   []string
+}
+
+func (e *DuplicateHeaderError) Error() string {
+	return fmt.Sprintf(
+		"duplicate header. key:%s sheetName:%s header:%s columns:%s",
+		e.SheetKey, e.SheetName, e.Header, strings.Join(e.Columns, ","),
+	)
+}
+
+func (e *DuplicateHeaderError) Is(target error) bool {
+	return target == ErrDuplicateHeader
+}
+
+type MissingHeaderError struct {
+	SheetKey  string
+	SheetName string
+	Headers   []string
+}
+
+func (e *MissingHeaderError) Error() string {
+	return fmt.Sprintf(
+		"missing header. key:%s sheetName:%s headers:%s",
+		e.SheetKey, e.SheetName, strings.Join(e.Headers, ","),
+	)
+}
+
+func (e *MissingHeaderError) Is(target error) bool {
+	return target == ErrHeaderNotFound
+}
+
+// joinHeaders builds the header of each column from one or more header
+// rows. Columns whose header cells are all blank are left out.
+func joinHeaders(rows [][]interface{}, cols int, sep string) ([]string, []int) {
+	var (
+		headers       = make([]string, 0, cols)
+		headerIndexes = make([]int, 0, cols)
+		carry         = make([]string, len(rows))
+	)
+	for i := 0; i < cols; i++ {
+		var (
+			own   = make([]string, len(rows))
+			parts = make([]string, len(rows))
+			lower = false
+		)
+		for l := range rows {
+			if i < len(rows[l]) {
+				own[l] = newCell(rows[l][i], false).String()
+			}
+			if own[l] != "" {
+				carry[l] = own[l]
+				for u := l + 1; u < len(rows); u++ {
+					carry[u] = ""
+				}
+			}
+		}
+		for l := len(rows) - 1; 0 <= l; l-- {
+			s := own[l]
+			if s == "" && lower {
+				s = carry[l]
+			}
+			if s != "" {
+				parts[l] = s
+				lower = true
+			}
+		}
+		header := []string{}
+		for _, p := range parts {
+			if p != "" {
+				header = append(header, p)
+			}
+		}
+		if 0 < len(header) {
+			headers = append(headers, strings.Join(header, sep))
+			headerIndexes = append(headerIndexes, i)
+		}
+	}
+	return headers, headerIndexes
+}
+
+// checkHeaderRows validates the HeaderRow and MultiRowHeader options.
+func (ws *Worksheet) checkHeaderRows() error {
+	if ws.headerRow < 0 || ws.headerRows <= 0 {
+		return fmt.Errorf(
+			"%w. invalid header rows. key:%s sheetName:%s row:%d rows:%d",
+			ErrNoHeader, ws.sheetKey, ws.sheetName, ws.headerRow+1, ws.headerRows,
+		)
+	}
+	return nil
+}
+
+// parseHeaders builds the headers from the header rows of values, the
+// rows of the sheet from the top.
+func (ws *Worksheet) parseHeaders(values [][]interface{}) ([]string, []int, int, error) {
+	if err := ws.checkHeaderRows(); err != nil {
+		return nil, nil, 0, err
+	}
+	headerEnd := ws.headerRow + ws.headerRows
+	if len(values) < headerEnd {
+		return nil, nil, 0, fmt.Errorf("%w. key:%s sheetName:%s", ErrNoHeader, ws.sheetKey, ws.sheetName)
+	}
+	var (
+		headerRows = values[ws.headerRow:headerEnd]
+		cols       = 0
+	)
+	for _, row := range headerRows {
+		if cols < len(row) {
+			cols = len(row)
+		}
+	}
+	headers, headerIndexes := joinHeaders(headerRows, cols, ws.headerSeparator)
+	if err := ws.checkHeaders(headers, headerIndexes); err != nil {
+		return nil, nil, 0, err
+	}
+	return headers, headerIndexes, cols, nil
+}
+
+// project keeps the headers selected with the Columns option, in sheet
+// order, or returns a *MissingHeaderError.
+func (ws *Worksheet) project(headers []string, headerIndexes []int) ([]string, []int, error) {
+	if len(ws.columns) <= 0 {
+		return headers, headerIndexes, nil
+	}
+	selected := make(map[string]bool, len(ws.columns))
+	for _, h := range ws.columns {
+		selected[h] = true
+	}
+	var (
+		resHeaders = make([]string, 0, len(ws.columns))
+		resIndexes = make([]int, 0, len(ws.columns))
+	)
+	for j, h := range headers {
+		if selected[h] {
+			resHeaders = append(resHeaders, h)
+			resIndexes = append(resIndexes, headerIndexes[j])
+			delete(selected, h)
+		}
+	}
+	if 0 < len(selected) {
+		missing := []string{}
+		for _, h := range ws.columns {
+			if selected[h] {
+				missing = append(missing, h)
+			}
+		}
+		return nil, nil, &MissingHeaderError{
+			SheetKey:  ws.sheetKey,
+			SheetName: ws.sheetName,
+			Headers:   missing,
+		}
+	}
+	return resHeaders, resIndexes, nil
+}
+
+// checkHeaders returns a *DuplicateHeaderError for the first header that
+// appears more than once, or renames the duplicates to "name_2", "name_3"
+// and so on when ws.suffixDuplicates is set.
+func (ws *Worksheet) checkHeaders(headers []string, headerIndexes []int) error {
+	seen := make(map[string]int, len(headers))
+	for _, h := range headers {
+		seen[h]++
+	}
+	for i, h := range headers {
+		if seen[h] <= 1 {
+			continue
+		}
+		if !ws.suffixDuplicates {
+			columns := []string{}
+			for j := i; j < len(headers); j++ {
+				if headers[j] == h {
+					columns = append(columns, n2c(headerIndexes[j]+1))
+				}
+			}
+			return &DuplicateHeaderError{
+				SheetKey:  ws.sheetKey,
+				SheetName: ws.sheetName,
+				Header:    h,
+				Columns:   columns,
+			}
+		}
+		n := 2
+		for j := i + 1; j < len(headers); j++ {
+			if headers[j] != h {
+				continue
+			}
+			for {
+				name := fmt.Sprintf("%s_%d", h, n)
+				n++
+				if _, ok := seen[name]; !ok {
+					headers[j] = name
+					seen[name] = 1
+					break
+				}
+			}
+		}
+		seen[h] = 1
+	}
+	return nil
+}
+
+// RequireHeaders returns a *MissingHeaderError listing the headers that the
+// worksheet does not have.
+func (ws *Worksheet) RequireHeaders(headers ...string) error {
+	missing := []string{}
+	for _, h := range headers {
+		if _, ok := ws.headerIndex(h); !ok {
+			missing = append(missing, h)
+		}
+	}
+	if 0 < len(missing) {
+		return &MissingHeaderError{
+			SheetKey:  ws.sheetKey,
+			SheetName: ws.sheetName,
+			Headers:   missing,
+		}
+	}
+	return nil
+}
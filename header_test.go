@@ -0,0 +1,183 @@
+package gss
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinHeaders(t *testing.T) {
+	headers, headerIndexes := joinHeaders([][]interface{}{
+		[]interface{}{"", "column1", "", "column2", "column3"},
+	}, 5, ".")
+	assert.Equal(t, []string{"column1", "column2", "column3"}, headers)
+	assert.Equal(t, []int{1, 3, 4}, headerIndexes)
+
+	headers, headerIndexes = joinHeaders([][]interface{}{
+		[]interface{}{"id", "name", "", "score", "", ""},
+		[]interface{}{"", "first", "last", "math", "", "eng"},
+	}, 7, ".")
+	assert.Equal(t, []string{"id", "name.first", "name.last", "score.math", "score.eng"}, headers)
+	assert.Equal(t, []int{0, 1, 2, 3, 5}, headerIndexes)
+
+	headers, _ = joinHeaders([][]interface{}{
+		[]interface{}{"a", "", "", ""},
+		[]interface{}{"x", "", "y", ""},
+		[]interface{}{"1", "2", "1", "2"},
+	}, 4, "/")
+	assert.Equal(t, []string{"a/x/1", "a/x/2", "a/y/1", "a/y/2"}, headers)
+}
+
+func TestGetWorksheet_DuplicateHeader(t *testing.T) {
+	values := map[string]interface{}{
+		"range":          "'シート1'!A1:D2",
+		"majorDimension": "ROWS",
+		"values": []interface{}{
+			[]interface{}{"id", "name", "name_2", "name"},
+			[]interface{}{"1", "a", "b", "c"},
+		},
+	}
+	client, _ := newDummyClient(values, values)
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.Equal(t, &DuplicateHeaderError{
+		SheetKey:  "XXXXXX",
+		SheetName: "シート1",
+		Header:    "name",
+		Columns:   []string{"B", "D"},
+	}, err)
+
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", SuffixDuplicateHeaders())
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, []string{"id", "name", "name_2", "name_3"}, ws.Headers())
+	assert.Equal(t, map[string]string{
+		"id":     "1",
+		"name":   "a",
+		"name_2": "b",
+		"name_3": "c",
+	}, ws.Rows[0])
+}
+
+func TestWorksheetRequireHeaders(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.NoError(t, ws.RequireHeaders("column1", "column3"))
+	assert.Equal(t, &MissingHeaderError{
+		SheetKey:  "XXXXXX",
+		SheetName: "シート1",
+		Headers:   []string{"column4", "column5"},
+	}, ws.RequireHeaders("column1", "column4", "column5"))
+}
+
+func TestGetWorksheet_Columns(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "name", "price", "stock", "note"},
+		[]interface{}{"1", "a", "100", "5", "x"},
+		[]interface{}{"2", "b", "200", "6"},
+		[]interface{}{"", "", "", "", "total"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &countTransport{RoundTripper: s, count: map[string]int{}}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ss.GetWorksheet("XXXXXX", "シート1", Columns("id", "unknown"))
+	var missing *MissingHeaderError
+	assert.True(t, errors.As(err, &missing))
+	assert.Equal(t, []string{"unknown"}, missing.Headers)
+
+	tr.count = map[string]int{}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", Columns("price", "id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header rows, one BatchGet of both columns and the rows below them
+	assert.Equal(t, 3, tr.count["GET"])
+	assert.Equal(t, []string{"id", "price"}, ws.Headers())
+	// the row only the unloaded note column fills is loaded blank
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "1", "price": "100"},
+		map[string]string{"id": "2", "price": "200"},
+		map[string]string{"id": "", "price": ""},
+	}, ws.Rows)
+
+	ws.Rows[1]["price"] = "250"
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Append([]map[string]string{{"id": "3", "price": "300"}}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, ws.Rows, 4)
+	ws.Rows[3]["price"] = "350"
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name", "price", "stock", "note"},
+		[]interface{}{"1", "a", "100", "5", "x"},
+		[]interface{}{"2", "b", 250.0, "6"},
+		[]interface{}{"", "", "", "", "total"},
+		[]interface{}{3.0, "", 350.0},
+	}, vals)
+}
+
+func TestGetWorksheet_ColumnsSheetEnd(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"a", "b"},
+		[]interface{}{"x"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	ss, err := NewSpreadsheet(&http.Client{Transport: &landedTransport{s: s}}, Retry(newDummyRetryPolicy(&slept)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", Columns("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the row of column a is loaded blank, inserting at the end goes below it
+	assert.Len(t, ws.Rows, 1)
+	if err := ws.InsertRows(len(ws.Rows), []map[string]string{{"b": "new"}}); err != nil {
+		t.Fatal(err)
+	}
+	// a retried Append that landed is found below it too
+	if err := ws.Append([]map[string]string{{"b": "last"}}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(slept))
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"a", "b"},
+		[]interface{}{"x"},
+		[]interface{}{"", "new"},
+		[]interface{}{"", "last"},
+	}, vals)
+}
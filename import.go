@@ -0,0 +1,183 @@
+package gss
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type ImportMode int
+
+const (
+	// ImportReplace makes the rows of the sheet equal to the imported ones.
+	ImportReplace ImportMode = iota
+	// ImportAppend adds the imported rows after the last row.
+	ImportAppend
+	// ImportUpsert updates the rows whose key matches an imported row and
+	// appends the others, see SetKey.
+	ImportUpsert
+)
+
+// ImportResult reports what an import did. Missing lists the sheet headers
+// the input has no column for, they are left blank by ImportReplace and
+// ImportAppend and unchanged by ImportUpsert.
+type ImportResult struct {
+	Missing  []string
+	Updated  int
+	Appended int
+	Deleted  int
+}
+
+// UnknownColumnsError is returned when imported columns match no sheet
+// header, nothing is imported then.
+type UnknownColumnsError struct {
+	SheetKey  string
+	SheetName string
+	Columns   []string
+}
+
+func (e *UnknownColumnsError) Error() string {
+	return fmt.Sprintf(
+		"unknown columns. key:%s sheetName:%s columns:%s",
+		e.SheetKey, e.SheetName, strings.Join(e.Columns, ","),
+	)
+}
+
+func (e *UnknownColumnsError) Is(target error) bool {
+	return target == ErrHeaderNotFound
+}
+
+// checkColumns returns the sheet headers missing from columns, or an
+// *UnknownColumnsError.
+func (ws *Worksheet) checkColumns(columns []string) ([]string, error) {
+	var (
+		seen    = make(map[string]bool, len(columns))
+		unknown = []string{}
+		missing = []string{}
+	)
+	for _, c := range columns {
+		seen[c] = true
+		if _, ok := ws.headerIndex(c); !ok {
+			unknown = append(unknown, c)
+		}
+	}
+	if 0 < len(unknown) {
+		return nil, &UnknownColumnsError{
+			SheetKey:  ws.sheetKey,
+			SheetName: ws.sheetName,
+			Columns:   unknown,
+		}
+	}
+	for _, h := range ws.Headers() {
+		if !seen[h] {
+			missing = append(missing, h)
+		}
+	}
+	return missing, nil
+}
+
+// importRows sends rows with Update and Append in chunks of at most
+// MaxBatchCells cells.
+func (ws *Worksheet) importRows(ctx context.Context, rows []map[string]string, missing []string, mode ImportMode) (*ImportResult, error) {
+	var (
+		res = &ImportResult{Missing: missing}
+		err error
+	)
+	switch mode {
+	case ImportReplace:
+		err = ws.importReplace(ctx, rows, res)
+	case ImportAppend:
+		err = ws.appendChunks(ctx, rows, res)
+	case ImportUpsert:
+		err = ws.importUpsert(ctx, rows, res)
+	default:
+		err = fmt.Errorf("%w. unknown import mode. mode:%d", ErrInvalidArgument, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// chunkRows splits rows so that each chunk holds at most MaxBatchCells
+// cells.
+func (ws *Worksheet) chunkRows(rows []map[string]string) [][]map[string]string {
+	n := len(rows)
+	if 0 < ws.MaxBatchCells && 0 < ws.cols {
+		n = ws.MaxBatchCells / ws.cols
+	}
+	if n <= 0 {
+		n = 1
+	}
+	res := [][]map[string]string{}
+	for 0 < len(rows) {
+		if len(rows) < n {
+			n = len(rows)
+		}
+		res = append(res, rows[:n])
+		rows = rows[n:]
+	}
+	return res
+}
+
+func (ws *Worksheet) appendChunks(ctx context.Context, rows []map[string]string, res *ImportResult) error {
+	for _, chunk := range ws.chunkRows(rows) {
+		if err := ws.AppendContext(ctx, chunk); err != nil {
+			return err
+		}
+		res.Appended += len(chunk)
+	}
+	return nil
+}
+
+// importReplace overwrites the existing rows in place, deletes the surplus
+// ones and appends the rest.
+func (ws *Worksheet) importReplace(ctx context.Context, rows []map[string]string, res *ImportResult) error {
+	ws.DiscardChanges()
+	n := len(rows)
+	if len(ws.Rows) < n {
+		n = len(ws.Rows)
+	}
+	for i, row := range rows[:n] {
+		for _, h := range ws.Headers() {
+			if ws.Rows[i][h] != row[h] {
+				ws.Rows[i][h] = row[h]
+			}
+		}
+	}
+	res.Updated = n
+	res.Deleted = len(ws.Rows) - n
+	ws.Rows = ws.Rows[:n]
+	if err := ws.UpdateContext(ctx); err != nil {
+		return err
+	}
+	return ws.appendChunks(ctx, rows[n:], res)
+}
+
+func (ws *Worksheet) importUpsert(ctx context.Context, rows []map[string]string, res *ImportResult) error {
+	index, err := ws.buildKeyIndex()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		v := row[ws.key]
+		if v == "" {
+			return fmt.Errorf("%w. header:%s row:%d", ErrNoKeyValue, ws.key, i)
+		}
+		if seen[v] {
+			return fmt.Errorf("%w. header:%s value:%s", ErrDuplicateKey, ws.key, v)
+		}
+		seen[v] = true
+		if _, ok := index[v]; ok {
+			res.Updated++
+		}
+	}
+	for _, chunk := range ws.chunkRows(rows) {
+		if err := ws.UpsertContext(ctx, chunk); err != nil {
+			return err
+		}
+	}
+	res.Appended = len(rows) - res.Updated
+	return nil
+}
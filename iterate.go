@@ -0,0 +1,87 @@
+package gss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStopIteration can be returned by the function passed to Iterate to
+// stop early, Iterate then returns nil.
+var ErrStopIteration = errors.New("stop iteration")
+
+func (ws *Worksheet) rowsLoaded() error {
+	if ws.headerOnly {
+		return fmt.Errorf("%w. key:%s sheetName:%s", ErrRowsNotLoaded, ws.sheetKey, ws.sheetName)
+	}
+	return nil
+}
+
+func (ws *Worksheet) Iterate(batchRows int, fn func(i int, row map[string]string) error) error {
+	return ws.IterateContext(context.Background(), batchRows, fn)
+}
+
+// IterateContext reads the data rows in pages of batchRows rows, e.g.
+// "Sheet1!A2:Z5001", and calls fn with each row that is not blank. i is the
+// index the row has in ws.Rows after a full Refresh. Only one page is held
+// in memory and ws.Rows is left untouched; the rows are parsed against the
+// headers of the last Refresh.
+func (ws *Worksheet) IterateContext(ctx context.Context, batchRows int, fn func(i int, row map[string]string) error) error {
+	if batchRows <= 0 {
+		return fmt.Errorf("%w. invalid batch rows. batchRows:%d", ErrInvalidArgument, batchRows)
+	}
+	rowCount, err := ws.rowCount(ctx)
+	if err != nil {
+		return err
+	}
+	for start := ws.dataRow(); start <= rowCount; start += batchRows {
+		end := start + batchRows - 1
+		if rowCount < end {
+			end = rowCount
+		}
+		r, err := ws.getValues(ctx, fmt.Sprintf("%s!A%d:%s%d", ws.sheetName, start, n2c(ws.cols), end))
+		if err != nil {
+			return err
+		}
+		for j, vals := range r.Values {
+			cells := ws.newCells(vals, ws.cols)
+			if blankCells(cells) {
+				continue
+			}
+			err := fn(start-ws.dataRow()+j, ws.newRow(cells))
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rowCount returns the number of rows of the sheet grid.
+func (ws *Worksheet) rowCount(ctx context.Context) (int, error) {
+	r, err := fetchSpreadsheet(ctx, ws.service, ws.caller, ws.sheetKey)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range r.Sheets {
+		if p := s.Properties; p != nil && p.Title == ws.sheetName {
+			if p.GridProperties == nil {
+				return 0, nil
+			}
+			return int(p.GridProperties.RowCount), nil
+		}
+	}
+	return 0, sheetNotFound(ws.sheetKey, ws.sheetName)
+}
+
+func blankCells(cells []Cell) bool {
+	for _, c := range cells {
+		if c.String() != "" {
+			return false
+		}
+	}
+	return true
+}
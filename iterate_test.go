@@ -0,0 +1,119 @@
+package gss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorksheetIterate(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "name"},
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+		[]interface{}{},
+		[]interface{}{"4", "d"},
+		[]interface{}{"5", "e"},
+		[]interface{}{"6", "f"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &countTransport{RoundTripper: s, count: map[string]int{}}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := &sheets.SheetProperties{
+		GridProperties: &sheets.GridProperties{RowCount: 8},
+	}
+	if err := ss.updateSheetProperties(context.Background(), "XXXXXX", "シート1", props, "gridProperties.rowCount"); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", HeaderOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"id", "name"}, ws.Headers())
+	assert.Equal(t, []map[string]string{}, ws.Rows)
+	assert.True(t, errors.Is(ws.Append([]map[string]string{{"id": "7"}}), ErrRowsNotLoaded))
+	assert.True(t, errors.Is(ws.Update(), ErrRowsNotLoaded))
+
+	tr.count = map[string]int{}
+	var (
+		indexes = []int{}
+		rows    = []map[string]string{}
+	)
+	err = ws.Iterate(3, func(i int, row map[string]string) error {
+		indexes = append(indexes, i)
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one Get for the grid size and three pages of the seven data rows
+	assert.Equal(t, 4, tr.count["GET"])
+	assert.Equal(t, []int{0, 1, 3, 4, 5}, indexes)
+	assert.Equal(t, map[string]string{"id": "4", "name": "d"}, rows[2])
+	assert.Equal(t, map[string]string{"id": "6", "name": "f"}, rows[4])
+
+	tr.count = map[string]int{}
+	n := 0
+	err = ws.Iterate(2, func(i int, row map[string]string) error {
+		if n++; n == 2 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, tr.count["GET"])
+
+	errFn := errors.New("fn")
+	err = ws.Iterate(2, func(i int, row map[string]string) error {
+		return errFn
+	})
+	assert.Equal(t, errFn, err)
+	assert.Error(t, ws.Iterate(0, nil))
+}
+
+func TestBatch_HeaderOnly(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "name"},
+		[]interface{}{"1", "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := NewSpreadsheet(&http.Client{Transport: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", HeaderOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := ss.Batch("XXXXXX")
+	assert.True(t, errors.Is(b.Append(ws, []map[string]string{{"id": "2"}}), ErrRowsNotLoaded))
+	assert.True(t, errors.Is(b.Update(ws), ErrRowsNotLoaded))
+	assert.NoError(t, b.Commit())
+
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name"},
+		[]interface{}{"1", "a"},
+	}, vals)
+}
@@ -0,0 +1,238 @@
+package gss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// MarshalJSON encodes ws.Rows, including uncommitted edits, as an array of
+// objects whose keys follow the column order of the sheet. Columns with a
+// blank header are keyed by their letter, e.g. "C", and hold the values of
+// the last Refresh.
+func (ws *Worksheet) MarshalJSON() ([]byte, error) {
+	rowIndexes, err := ws.rowIndexes()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		columns = ws.jsonColumns()
+		b       = []byte{'['}
+	)
+	for i, row := range ws.Rows {
+		if 0 < i {
+			b = append(b, ',')
+		}
+		if b, err = appendJSONRow(b, columns, row, ws.values[rowIndexes[i]]); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, ']'), nil
+}
+
+// MarshalJSONL writes ws.Rows as JSON Lines, one object per row with its
+// keys like MarshalJSON.
+func (ws *Worksheet) MarshalJSONL(w io.Writer) error {
+	rowIndexes, err := ws.rowIndexes()
+	if err != nil {
+		return err
+	}
+	var (
+		columns = ws.jsonColumns()
+		b       []byte
+	)
+	for i, row := range ws.Rows {
+		if b, err = appendJSONRow(b[:0], columns, row, ws.values[rowIndexes[i]]); err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonColumn is a key of the objects MarshalJSON writes, header is false
+// for the columns keyed by their letter.
+type jsonColumn struct {
+	key    string
+	col    int
+	header bool
+}
+
+// jsonColumns lists the headers and the columns with a blank header in
+// sheet order. With the Columns option only the loaded headers are listed,
+// as are blank columns whose letter is also a header.
+func (ws *Worksheet) jsonColumns() []jsonColumn {
+	var (
+		res     = []jsonColumn{}
+		headers = make(map[int]string, len(ws.headers))
+		names   = make(map[string]bool, len(ws.headers))
+	)
+	for j, h := range ws.headers {
+		if h != "" {
+			headers[ws.headerIndexes[j]] = h
+			names[h] = true
+		}
+	}
+	for c := 0; c < ws.cols; c++ {
+		if h, ok := headers[c]; ok {
+			res = append(res, jsonColumn{key: h, col: c, header: true})
+		} else if len(ws.columns) <= 0 && !names[n2c(c+1)] {
+			res = append(res, jsonColumn{key: n2c(c + 1), col: c})
+		}
+	}
+	return res
+}
+
+func appendJSONRow(b []byte, columns []jsonColumn, row map[string]string, vals []Cell) ([]byte, error) {
+	b = append(b, '{')
+	for i, c := range columns {
+		if 0 < i {
+			b = append(b, ',')
+		}
+		s := vals[c.col].String()
+		if c.header {
+			s = row[c.key]
+		}
+		k, err := json.Marshal(c.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, k...)
+		b = append(b, ':')
+		b = append(b, v...)
+	}
+	return append(b, '}'), nil
+}
+
+// UnmarshalJSONL decodes JSON Lines into rows that can be passed to Append
+// or Upsert. Numbers keep their text, booleans become TRUE/FALSE and null
+// becomes "". The letter keys of blank header columns are accepted but not
+// imported, other keys that are not headers return an *UnknownColumnsError.
+func (ws *Worksheet) UnmarshalJSONL(r io.Reader) ([]map[string]string, error) {
+	rows, _, err := ws.readJSONL(r)
+	return rows, err
+}
+
+func (ws *Worksheet) ImportJSONL(r io.Reader, mode ImportMode) (*ImportResult, error) {
+	return ws.ImportJSONLContext(context.Background(), r, mode)
+}
+
+// ImportJSONLContext applies the objects of a JSON Lines stream like
+// ImportCSVContext applies records.
+func (ws *Worksheet) ImportJSONLContext(ctx context.Context, r io.Reader, mode ImportMode) (*ImportResult, error) {
+	rows, missing, err := ws.readJSONL(r)
+	if err != nil {
+		return nil, err
+	}
+	return ws.importRows(ctx, rows, missing, mode)
+}
+
+func (ws *Worksheet) ImportJSON(r io.Reader, mode ImportMode) (*ImportResult, error) {
+	return ws.ImportJSONContext(context.Background(), r, mode)
+}
+
+// ImportJSONContext applies a JSON array of objects, the form MarshalJSON
+// writes.
+func (ws *Worksheet) ImportJSONContext(ctx context.Context, r io.Reader, mode ImportMode) (*ImportResult, error) {
+	var objs []map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&objs); err != nil {
+		return nil, err
+	}
+	rows, missing, err := ws.jsonRows(objs)
+	if err != nil {
+		return nil, err
+	}
+	return ws.importRows(ctx, rows, missing, mode)
+}
+
+func (ws *Worksheet) readJSONL(r io.Reader) ([]map[string]string, []string, error) {
+	var (
+		objs = []map[string]interface{}{}
+		dec  = json.NewDecoder(r)
+	)
+	dec.UseNumber()
+	for {
+		var obj map[string]interface{}
+		err := dec.Decode(&obj)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid json line. line:%d: %w", len(objs)+1, err)
+		}
+		objs = append(objs, obj)
+	}
+	return ws.jsonRows(objs)
+}
+
+// jsonRows converts decoded objects to rows and returns the headers that
+// none of them has. The keys of blank header columns are left out.
+func (ws *Worksheet) jsonRows(objs []map[string]interface{}) ([]map[string]string, []string, error) {
+	var (
+		rows    = make([]map[string]string, 0, len(objs))
+		columns = []string{}
+		seen    = map[string]bool{}
+		blank   = map[string]bool{}
+	)
+	for _, c := range ws.jsonColumns() {
+		if !c.header {
+			blank[c.key] = true
+		}
+	}
+	for i, obj := range objs {
+		var (
+			row  = make(map[string]string, len(obj))
+			keys = make([]string, 0, len(obj))
+		)
+		for k := range obj {
+			if !blank[k] {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s, err := jsonValue(obj[k])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w. header:%s row:%d", err, k, i)
+			}
+			row[k] = s
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+		rows = append(rows, row)
+	}
+	missing, err := ws.checkColumns(columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, missing, nil
+}
+
+var errJSONValue = errors.New("unsupported json value")
+
+func jsonValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return BoolCell(t).String(), nil
+	}
+	return "", errJSONValue
+}
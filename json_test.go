@@ -0,0 +1,133 @@
+package gss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorksheetMarshalJSON(t *testing.T) {
+	_, ws := newCSVWorksheet(t)
+	ws.Rows = ws.Rows[:2]
+	ws.Rows[1]["note"] = "\"q\""
+
+	b, err := json.Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `[{"id":"1","name":"a","note":"x"},{"id":"2","name":"b","note":"\"q\""}]`, string(b))
+
+	var buf bytes.Buffer
+	if err := ws.MarshalJSONL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "{\"id\":\"1\",\"name\":\"a\",\"note\":\"x\"}\n{\"id\":\"2\",\"name\":\"b\",\"note\":\"\\\"q\\\"\"}\n", buf.String())
+
+	rows, err := ws.UnmarshalJSONL(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ws.Rows, rows)
+}
+
+func TestWorksheetMarshalJSON_BlankHeader(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "", "note"},
+		[]interface{}{"1", "memo", "x"},
+		[]interface{}{"2", "", "y"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.Rows[0]["note"] = "X"
+
+	b, err := json.Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `[{"id":"1","B":"memo","note":"X"},{"id":"2","B":"","note":"y"}]`, string(b))
+
+	// the letter keys are accepted back, the column is left as is
+	if err := ws.SetKey("id"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ws.ImportJSON(bytes.NewReader(b), ImportUpsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ImportResult{Missing: []string{}, Updated: 2}, res)
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{"1", "memo", "X"}, vals[1])
+}
+
+func TestWorksheetUnmarshalJSONL(t *testing.T) {
+	_, ws := newCSVWorksheet(t)
+	rows, err := ws.UnmarshalJSONL(strings.NewReader("{\"id\":4,\"name\":null}\n\n{\"id\":\"5\",\"note\":true}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "4", "name": ""},
+		map[string]string{"id": "5", "note": "TRUE"},
+	}, rows)
+
+	_, err = ws.UnmarshalJSONL(strings.NewReader(`{"id":1,"b":2,"a":3}`))
+	var unknown *UnknownColumnsError
+	assert.True(t, errors.As(err, &unknown))
+	assert.Equal(t, []string{"a", "b"}, unknown.Columns)
+	_, err = ws.UnmarshalJSONL(strings.NewReader(`{"id":[1]}`))
+	assert.True(t, errors.Is(err, errJSONValue))
+	_, err = ws.UnmarshalJSONL(strings.NewReader("{\"id\":1}\n{"))
+	assert.Error(t, err)
+}
+
+func TestWorksheetImportJSON(t *testing.T) {
+	s, ws := newCSVWorksheet(t)
+	if err := ws.SetKey("id"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ws.ImportJSONL(strings.NewReader("{\"id\":\"3\",\"note\":\"Z\"}\n{\"id\":\"4\",\"name\":\"d\"}\n"), ImportUpsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ImportResult{Missing: []string{}, Updated: 1, Appended: 1}, res)
+
+	res, err = ws.ImportJSON(strings.NewReader(`[{"id":"5","name":"e","note":"v"}]`), ImportAppend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ImportResult{Missing: []string{}, Appended: 1}, res)
+	_, err = ws.ImportJSON(strings.NewReader(`{"id":"6"}`), ImportAppend)
+	assert.Error(t, err)
+
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "name", "note"},
+		[]interface{}{"1", "a", "x"},
+		[]interface{}{"2", "b", "y"},
+		[]interface{}{"3", "c", "Z"},
+		[]interface{}{4.0, "d"},
+		[]interface{}{5.0, "e", "v"},
+	}, vals)
+}
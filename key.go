@@ -0,0 +1,114 @@
+package gss
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetKey makes header the primary key column used by FindByKey and Upsert.
+func (ws *Worksheet) SetKey(header string) error {
+	if _, ok := ws.headerIndex(header); !ok {
+		return headerNotFound(header)
+	}
+	ws.key = header
+	ws.keyIndex = nil
+	_, err := ws.buildKeyIndex()
+	return err
+}
+
+func (ws *Worksheet) Key() string {
+	return ws.key
+}
+
+func (ws *Worksheet) headerIndex(header string) (int, bool) {
+	for j, h := range ws.headers {
+		if h == header {
+			return ws.headerIndexes[j], true
+		}
+	}
+	return 0, false
+}
+
+func (ws *Worksheet) buildKeyIndex() (map[string]int, error) {
+	if ws.key == "" {
+		return nil, fmt.Errorf("%w. key:%s sheetName:%s", ErrNoKey, ws.sheetKey, ws.sheetName)
+	}
+	index := make(map[string]int, len(ws.Rows))
+	for i, row := range ws.Rows {
+		v := row[ws.key]
+		if v == "" {
+			continue
+		}
+		if j, ok := index[v]; ok {
+			return nil, fmt.Errorf("%w. header:%s value:%s rows:%d,%d", ErrDuplicateKey, ws.key, v, j, i)
+		}
+		index[v] = i
+	}
+	ws.keyIndex = index
+	return index, nil
+}
+
+// FindByKey returns the entry of ws.Rows whose key column equals value.
+// Edits made to the returned row are sent by Update.
+func (ws *Worksheet) FindByKey(value string) (map[string]string, bool, error) {
+	if i, ok := ws.keyIndex[value]; ok && i < len(ws.Rows) && ws.Rows[i][ws.key] == value {
+		return ws.Rows[i], true, nil
+	}
+	index, err := ws.buildKeyIndex()
+	if err != nil {
+		return nil, false, err
+	}
+	if i, ok := index[value]; ok {
+		return ws.Rows[i], true, nil
+	}
+	return nil, false, nil
+}
+
+// Upsert updates the rows whose key already exists in place and appends
+// the others.
+func (ws *Worksheet) Upsert(rows []map[string]string) error {
+	return ws.UpsertContext(context.Background(), rows)
+}
+
+func (ws *Worksheet) UpsertContext(ctx context.Context, rows []map[string]string) error {
+	index, err := ws.buildKeyIndex()
+	if err != nil {
+		return err
+	}
+	var (
+		appends = []map[string]string{}
+		seen    = make(map[string]bool, len(rows))
+	)
+	for i, row := range rows {
+		v := row[ws.key]
+		if v == "" {
+			return fmt.Errorf("%w. header:%s row:%d", ErrNoKeyValue, ws.key, i)
+		}
+		if seen[v] {
+			return fmt.Errorf("%w. header:%s value:%s", ErrDuplicateKey, ws.key, v)
+		}
+		seen[v] = true
+		for k := range row {
+			if _, ok := ws.headerIndex(k); !ok {
+				return headerNotFound(k)
+			}
+		}
+		if _, ok := index[v]; !ok {
+			appends = append(appends, row)
+		}
+	}
+	for _, row := range rows {
+		if i, ok := index[row[ws.key]]; ok {
+			for k, v := range row {
+				ws.Rows[i][k] = v
+			}
+		}
+	}
+	if err := ws.UpdateContext(ctx); err != nil {
+		return err
+	}
+	if len(appends) <= 0 {
+		return nil
+	}
+	return ws.AppendContext(ctx, appends)
+}
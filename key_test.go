@@ -0,0 +1,140 @@
+package gss
+
+import (
+	"encoding/json"
+	"testing"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorksheetSetKey(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Error(t, ws.SetKey("unknown"))
+	assert.NoError(t, ws.SetKey("column1"))
+	assert.Equal(t, "column1", ws.Key())
+
+	ws.Rows[1]["column2"] = "4"
+	assert.Error(t, ws.SetKey("column2"))
+}
+
+func TestWorksheetFindByKey(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	_, _, err = ws.FindByKey("2")
+	assert.Error(t, err)
+
+	err = ws.SetKey("column1")
+	if err != nil {
+		t.Error(err)
+	}
+	row, ok, err := ws.FindByKey("2")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.True(t, ok)
+	assert.Equal(t, "5", row["column2"])
+
+	ws.Rows[0]["column1"] = "10"
+	row, ok, err = ws.FindByKey("10")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.True(t, ok)
+	assert.Equal(t, "4", row["column2"])
+
+	_, ok, err = ws.FindByKey("1")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.False(t, ok)
+}
+
+func TestWorksheetUpsert(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.SetKey("column1")
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.Upsert([]map[string]string{
+		map[string]string{
+			"column1": "2",
+			"column2": "99",
+		},
+		map[string]string{
+			"column1": "4",
+			"column2": "10",
+			"column3": "11",
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData interface{}
+	err = json.NewDecoder(m.req[0].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!D3:D3",
+				"values": []interface{}{
+					[]interface{}{"99"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData = map[string]interface{}{
+		"majorDimension": "ROWS",
+		"values": []interface{}{
+			[]interface{}{nil, "4", nil, "10", "11"},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, [][]string{
+		[]string{"", "1", "", "4", "7"},
+		[]string{"", "2", "", "99", "8"},
+		[]string{"", "3", "", "6", "9"},
+		[]string{"", "4", "", "10", "11"},
+	}, ws.Values())
+
+	assert.Error(t, ws.Upsert([]map[string]string{
+		map[string]string{"column2": "1"},
+	}))
+	assert.Error(t, ws.Upsert([]map[string]string{
+		map[string]string{"column1": "5"},
+		map[string]string{"column1": "5"},
+	}))
+	assert.Error(t, ws.Upsert([]map[string]string{
+		map[string]string{"column1": "5", "unknown": "1"},
+	}))
+}
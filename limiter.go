@@ -0,0 +1,157 @@
+package gss
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type CallKind int
+
+const (
+	ReadCall CallKind = iota
+	WriteCall
+)
+
+// Limiter is consulted before each API call, retries included. One Limiter
+// can be shared by several Spreadsheets to keep them under a common quota.
+type Limiter interface {
+	Wait(ctx context.Context, kind CallKind) error
+}
+
+// TokenBucket allows rate calls per second on average with bursts of up to
+// burst calls. A rate of 0 or less means no limit.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewTokenBucket returns a bucket that never waits when perMinute is 0 or
+// less.
+func NewTokenBucket(perMinute, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait for it.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate <= 0 {
+		return 0
+	}
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.burst < b.tokens {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens--
+	if 0 <= b.tokens {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a token taken by reserve.
+func (b *TokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+type WaitStats struct {
+	Calls     int64
+	Delayed   int64
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// QuotaLimiter limits read and write calls with separate token buckets and
+// records how long calls waited.
+type QuotaLimiter struct {
+	Read  *TokenBucket
+	Write *TokenBucket
+
+	mu    sync.Mutex
+	stats [2]WaitStats
+	wait  func(context.Context, time.Duration) error
+}
+
+// NewQuotaLimiter returns a limiter allowing the given number of calls per
+// minute, with bursts of a tenth of that. 0 or less does not limit that kind
+// of calls.
+func NewQuotaLimiter(readPerMinute, writePerMinute int) *QuotaLimiter {
+	burst := func(n int) int {
+		if n < 10 {
+			return 1
+		}
+		return n / 10
+	}
+	return &QuotaLimiter{
+		Read:  NewTokenBucket(readPerMinute, burst(readPerMinute)),
+		Write: NewTokenBucket(writePerMinute, burst(writePerMinute)),
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (l *QuotaLimiter) Wait(ctx context.Context, kind CallKind) error {
+	b := l.Read
+	if kind == WriteCall {
+		b = l.Write
+	}
+	var d time.Duration
+	if b != nil {
+		d = b.reserve()
+	}
+	l.mu.Lock()
+	s := &l.stats[kind]
+	s.Calls++
+	if 0 < d {
+		s.Delayed++
+		s.TotalWait += d
+		if s.MaxWait < d {
+			s.MaxWait = d
+		}
+	}
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	wait := l.wait
+	if wait == nil {
+		wait = sleepContext
+	}
+	if err := wait(ctx, d); err != nil {
+		b.cancel()
+		return err
+	}
+	return nil
+}
+
+// Stats returns the number of calls and the time they waited so far.
+func (l *QuotaLimiter) Stats() (read, write WaitStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats[ReadCall], l.stats[WriteCall]
+}
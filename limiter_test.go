@@ -0,0 +1,108 @@
+package gss
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)
+	b := NewTokenBucket(60, 2)
+	b.now = func() time.Time { return now }
+	assert.Equal(t, time.Duration(0), b.reserve())
+	assert.Equal(t, time.Duration(0), b.reserve())
+	assert.Equal(t, 1*time.Second, b.reserve())
+	assert.Equal(t, 2*time.Second, b.reserve())
+	now = now.Add(3 * time.Second)
+	assert.Equal(t, time.Duration(0), b.reserve())
+	now = now.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), b.reserve())
+	assert.Equal(t, time.Duration(0), b.reserve())
+	assert.Equal(t, 1*time.Second, b.reserve())
+	b.cancel()
+	assert.Equal(t, 1*time.Second, b.reserve())
+
+	for _, perMinute := range []int{0, -60} {
+		b = NewTokenBucket(perMinute, 1)
+		b.now = func() time.Time { return now }
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, time.Duration(0), b.reserve())
+		}
+	}
+}
+
+func TestQuotaLimiter(t *testing.T) {
+	now := time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)
+	l := NewQuotaLimiter(600, 6)
+	l.Read.now = func() time.Time { return now }
+	l.Write.now = func() time.Time { return now }
+	var waited []time.Duration
+	l.wait = func(ctx context.Context, d time.Duration) error {
+		waited = append(waited, d)
+		return ctx.Err()
+	}
+	for i := 0; i < 61; i++ {
+		assert.NoError(t, l.Wait(context.Background(), ReadCall))
+	}
+	assert.NoError(t, l.Wait(context.Background(), WriteCall))
+	assert.NoError(t, l.Wait(context.Background(), WriteCall))
+	read, write := l.Stats()
+	assert.Equal(t, WaitStats{
+		Calls:     61,
+		Delayed:   1,
+		TotalWait: 100 * time.Millisecond,
+		MaxWait:   100 * time.Millisecond,
+	}, read)
+	assert.Equal(t, WaitStats{
+		Calls:     2,
+		Delayed:   1,
+		TotalWait: 10 * time.Second,
+		MaxWait:   10 * time.Second,
+	}, write)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 10 * time.Second}, waited)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, l.Wait(ctx, WriteCall))
+}
+
+func TestSpreadsheet_Limit(t *testing.T) {
+	l := NewQuotaLimiter(600, 600)
+	values := map[string]interface{}{
+		"range":          "'シート1'!A1:B2",
+		"majorDimension": "ROWS",
+		"values": []interface{}{
+			[]interface{}{"column1", "column2"},
+			[]interface{}{"1", "2"},
+		},
+	}
+	client, _ := newDummyClient(values, map[string]interface{}{"spreadsheetId": "XXXXXX"})
+	ss1, err := NewSpreadsheet(client, Limit(l))
+	if err != nil {
+		t.Error(err)
+	}
+	ws, err := ss1.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Error(err)
+	}
+	ws.Rows[0]["column1"] = "9"
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	client, _ = newDummyClient(values)
+	ss2, err := NewSpreadsheet(client, Limit(l))
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = ss2.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Error(err)
+	}
+	read, write := l.Stats()
+	assert.Equal(t, int64(2), read.Calls)
+	assert.Equal(t, int64(1), write.Calls)
+}
@@ -0,0 +1,126 @@
+package gss
+
+import (
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+type SpreadsheetOption func(*Spreadsheet)
+
+// Retry makes the Spreadsheet and the Worksheets it returns retry failed
+// API calls according to p.
+func Retry(p RetryPolicy) SpreadsheetOption {
+	return func(ss *Spreadsheet) {
+		ss.caller.retry = &p
+	}
+}
+
+// Limit makes the Spreadsheet and the Worksheets it returns wait for l
+// before each API call.
+func Limit(l Limiter) SpreadsheetOption {
+	return func(ss *Spreadsheet) {
+		ss.caller.limiter = l
+	}
+}
+
+// NoSheetIdCache makes every call that needs a sheet ID fetch the sheets of
+// the spreadsheet instead of keeping them, e.g. when other clients rename
+// or recreate sheets often.
+func NoSheetIdCache() SpreadsheetOption {
+	return func(ss *Spreadsheet) {
+		ss.sheetIds.disabled = true
+	}
+}
+
+type SheetOption func(*sheets.SheetProperties)
+
+// SheetIndex inserts the new sheet at index in the tab order.
+func SheetIndex(index int) SheetOption {
+	return func(p *sheets.SheetProperties) {
+		p.Index = int64(index)
+		p.ForceSendFields = append(p.ForceSendFields, "Index")
+	}
+}
+
+func SheetGridSize(rows, cols int) SheetOption {
+	return func(p *sheets.SheetProperties) {
+		p.GridProperties = gridProperties(p)
+		p.GridProperties.RowCount = int64(rows)
+		p.GridProperties.ColumnCount = int64(cols)
+	}
+}
+
+func SheetFrozen(rows, cols int) SheetOption {
+	return func(p *sheets.SheetProperties) {
+		p.GridProperties = gridProperties(p)
+		p.GridProperties.FrozenRowCount = int64(rows)
+		p.GridProperties.FrozenColumnCount = int64(cols)
+	}
+}
+
+func SheetHidden() SheetOption {
+	return func(p *sheets.SheetProperties) {
+		p.Hidden = true
+	}
+}
+
+func SheetTabColor(c Color) SheetOption {
+	return func(p *sheets.SheetProperties) {
+		p.TabColor = c.color()
+	}
+}
+
+func gridProperties(p *sheets.SheetProperties) *sheets.GridProperties {
+	if p.GridProperties == nil {
+		return &sheets.GridProperties{}
+	}
+	return p.GridProperties
+}
+
+type WorksheetOption func(*Worksheet)
+
+// HeaderRow sets the A1 row number of the header, rows above it are ignored.
+// GetWorksheet returns ErrNoHeader for a row below 1.
+func HeaderRow(row int) WorksheetOption {
+	return func(ws *Worksheet) {
+		ws.headerRow = row - 1
+	}
+}
+
+// MultiRowHeader reads the header from rows consecutive rows and joins the
+// levels of each column with sep, e.g. "name.first". Blank cells of upper
+// levels inherit the value on their left, as horizontally merged cells do.
+func MultiRowHeader(rows int, sep string) WorksheetOption {
+	return func(ws *Worksheet) {
+		ws.headerRows = rows
+		ws.headerSeparator = sep
+	}
+}
+
+// SuffixDuplicateHeaders renames repeated headers to "name_2", "name_3" and
+// so on instead of failing with a *DuplicateHeaderError.
+func SuffixDuplicateHeaders() WorksheetOption {
+	return func(ws *Worksheet) {
+		ws.suffixDuplicates = true
+	}
+}
+
+// HeaderOnly makes GetWorksheet and Refresh read the header rows only, for
+// sheets too large to hold in memory. ws.Rows stays empty, read the rows
+// with Iterate; Update, Append, InsertRows, DeleteRows and Batch return
+// ErrRowsNotLoaded.
+func HeaderOnly() WorksheetOption {
+	return func(ws *Worksheet) {
+		ws.headerOnly = true
+	}
+}
+
+// Columns makes GetWorksheet and Refresh load only the columns of headers,
+// read with one Values.BatchGet after the header rows. Rows below the last
+// one with a value in those columns are read to find the end of the sheet
+// and loaded blank. The other columns are left untouched by Update and
+// Append.
+func Columns(headers ...string) WorksheetOption {
+	return func(ws *Worksheet) {
+		ws.columns = headers
+	}
+}
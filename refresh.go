@@ -0,0 +1,134 @@
+package gss
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+func (ws *Worksheet) batchGetValues(ctx context.Context, ranges []string) ([]*sheets.ValueRange, error) {
+	call := ws.service.Spreadsheets.Values.BatchGet(ws.sheetKey).Ranges(ranges...)
+	if ws.ValueRenderOption != "" {
+		call = call.ValueRenderOption(ws.ValueRenderOption)
+	}
+	if ws.DateTimeRenderOption != "" {
+		call = call.DateTimeRenderOption(ws.DateTimeRenderOption)
+	}
+	var r *sheets.BatchGetValuesResponse
+	err := ws.caller.read(ctx, func() (err error) {
+		r, err = call.Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(r.ValueRanges) != len(ranges) {
+		return nil, fmt.Errorf("unexpected value ranges. want:%d got:%d", len(ranges), len(r.ValueRanges))
+	}
+	return r.ValueRanges, nil
+}
+
+// RefreshRange re-reads ws.Rows[start:end] from the sheet. Those rows lose
+// their uncommitted edits, the other rows keep theirs.
+func (ws *Worksheet) RefreshRange(start, end int) error {
+	return ws.RefreshRangeContext(context.Background(), start, end)
+}
+
+func (ws *Worksheet) RefreshRangeContext(ctx context.Context, start, end int) error {
+	if err := ws.rowsLoaded(); err != nil {
+		return err
+	}
+	if start < 0 || len(ws.Rows) < start {
+		return rowOutOfRange(start, len(ws.Rows))
+	}
+	if end < start || len(ws.Rows) < end {
+		return rowOutOfRange(end, len(ws.Rows))
+	}
+	if start == end {
+		return nil
+	}
+	rowIndexes, err := ws.rowIndexes()
+	if err != nil {
+		return err
+	}
+	first, last := rowIndexes[start], rowIndexes[end-1]
+	r, err := ws.getValues(
+		ctx,
+		fmt.Sprintf("%s!A%d:%s%d", ws.sheetName, first+ws.dataRow(), n2c(ws.cols), last+ws.dataRow()),
+	)
+	if err != nil {
+		return err
+	}
+	ws.adoptRows()
+	// rows deleted locally in between are refreshed too, they stay deleted
+	for i := first; i <= last; i++ {
+		var vals []interface{}
+		if i-first < len(r.Values) {
+			vals = r.Values[i-first]
+		}
+		ws.values[i] = ws.newCells(vals, ws.cols)
+		for j, h := range ws.headers {
+			ws.resetCell(i, h, ws.values[i][ws.headerIndexes[j]])
+		}
+	}
+	return nil
+}
+
+// RefreshColumns re-reads the columns of headers for the rows of the last
+// Refresh. Those cells lose their uncommitted edits, the other cells keep
+// theirs.
+func (ws *Worksheet) RefreshColumns(headers ...string) error {
+	return ws.RefreshColumnsContext(context.Background(), headers...)
+}
+
+func (ws *Worksheet) RefreshColumnsContext(ctx context.Context, headers ...string) error {
+	if err := ws.rowsLoaded(); err != nil {
+		return err
+	}
+	var (
+		cols   = make([]int, len(headers))
+		ranges = make([]string, len(headers))
+		first  = ws.dataRow()
+		last   = first + len(ws.values) - 1
+	)
+	for k, h := range headers {
+		c, ok := ws.headerIndex(h)
+		if !ok {
+			return headerNotFound(h)
+		}
+		cols[k] = c
+		ranges[k] = fmt.Sprintf("%s!%s%d:%s%d", ws.sheetName, n2c(c+1), first, n2c(c+1), last)
+	}
+	if len(headers) <= 0 || len(ws.values) <= 0 {
+		return nil
+	}
+	vrs, err := ws.batchGetValues(ctx, ranges)
+	if err != nil {
+		return err
+	}
+	ws.adoptRows()
+	for k, h := range headers {
+		for i := range ws.values {
+			var vals []interface{}
+			if i < len(vrs[k].Values) {
+				vals = vrs[k].Values[i]
+			}
+			ws.values[i][cols[k]] = ws.newCells(vals, 1)[0]
+			ws.resetCell(i, h, ws.values[i][cols[k]])
+		}
+	}
+	return nil
+}
+
+// resetCell sets the snapshot row ws.origRows[i], which is also the entry
+// of ws.Rows unless it was deleted, to c and drops a pending SetCell.
+func (ws *Worksheet) resetCell(i int, header string, c Cell) {
+	row := ws.origRows[i]
+	row[header] = c.String()
+	delete(ws.pending, cellKey{
+		row:    reflect.ValueOf(row).Pointer(),
+		header: header,
+	})
+}
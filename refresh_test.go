@@ -0,0 +1,119 @@
+package gss
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRefreshWorksheets(t *testing.T) (*gsstest.Server, *countTransport, *Worksheet, *Worksheet) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "name", "note"},
+		[]interface{}{"1", "a", "x"},
+		[]interface{}{"2", "b", "y"},
+		[]interface{}{"3", "c", "z"},
+		[]interface{}{"4", "d", "w"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &countTransport{RoundTripper: s, count: map[string]int{}}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range remote.Rows {
+		row["name"] = row["name"] + "!"
+		row["note"] = row["note"] + "!"
+		if i == 1 {
+			row["note"] = "5"
+		}
+	}
+	if err := remote.Update(); err != nil {
+		t.Fatal(err)
+	}
+	tr.count = map[string]int{}
+	return s, tr, ws, remote
+}
+
+func TestWorksheetRefreshRange(t *testing.T) {
+	_, tr, ws, _ := newRefreshWorksheets(t)
+	ws.Rows[0]["name"] = "local0"
+	ws.Rows[2]["name"] = "local2"
+	ws.Rows[3]["name"] = "local3"
+	if err := ws.SetCell(2, "note", NumberCell(1)); err != nil {
+		t.Fatal(err)
+	}
+	// ws.Rows[1] is deleted locally, the range still covers it
+	ws.Rows = append(ws.Rows[:1], ws.Rows[2:]...)
+
+	if err := ws.RefreshRange(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, tr.count["GET"])
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "1", "name": "a!", "note": "x!"},
+		map[string]string{"id": "3", "name": "c!", "note": "z!"},
+		map[string]string{"id": "4", "name": "local3", "note": "w"},
+	}, ws.Rows)
+	assert.Equal(t, []string{"2", "b!", "5"}, ws.Values()[1])
+	c, err := ws.GetCell(1, "note")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StringCell("z!"), c)
+
+	assert.Error(t, ws.RefreshRange(2, 4))
+	assert.Error(t, ws.RefreshRange(-1, 1))
+	assert.NoError(t, ws.RefreshRange(1, 1))
+}
+
+func TestWorksheetRefreshColumns(t *testing.T) {
+	s, tr, ws, _ := newRefreshWorksheets(t)
+	ws.ValueRenderOption = "UNFORMATTED_VALUE"
+	ws.Rows[0]["name"] = "local0"
+	ws.Rows[1]["note"] = "local1"
+
+	if err := ws.RefreshColumns("note"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, tr.count["GET"])
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "1", "name": "local0", "note": "x!"},
+		map[string]string{"id": "2", "name": "b", "note": "5"},
+		map[string]string{"id": "3", "name": "c", "note": "z!"},
+		map[string]string{"id": "4", "name": "d", "note": "w!"},
+	}, ws.Rows)
+	c, err := ws.GetCell(1, "note")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, NumberCell(5), c)
+
+	// the refreshed cells are no longer changes, name of row 0 still is
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{"1", "local0", "x!"}, vals[1])
+	assert.Equal(t, []interface{}{"2", "b!", 5.0}, vals[2])
+
+	err = ws.RefreshColumns("note", "unknown")
+	assert.True(t, errors.Is(err, ErrHeaderNotFound))
+}
@@ -0,0 +1,101 @@
+package gss
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy retries API calls that failed with a retryable status code
+// or a network timeout, waiting InitialBackoff*Multiplier^n (at most
+// MaxBackoff) between attempts. Jitter randomizes each wait by up to that
+// fraction of it.
+//
+// Calls that are not idempotent, like structural BatchUpdate requests, are
+// only retried on 429 since the request may have been applied otherwise.
+// Append checks whether the rows of a failed attempt landed before
+// retrying.
+type RetryPolicy struct {
+	MaxAttempts     int
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+	Multiplier      float64
+	Jitter          float64
+	RetryableStatus []int
+
+	sleep func(context.Context, time.Duration) error
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 1 * time.Second,
+	MaxBackoff:     32 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryableStatus: []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt))
+	if 0 < p.MaxBackoff && float64(p.MaxBackoff) < d {
+		d = float64(p.MaxBackoff)
+	}
+	if 0 < p.Jitter {
+		d = d * (1 + p.Jitter*(2*rand.Float64()-1))
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, code := range p.RetryableStatus {
+			if apiErr.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func rateLimited(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+func (p *RetryPolicy) run(ctx context.Context, fn func(attempt int) error, retryable func(error) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p == nil {
+		return fn(0)
+	}
+	sleep := p.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn(attempt)
+		if err == nil || p.MaxAttempts <= attempt+1 || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if err := sleep(ctx, p.backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package gss
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDummyRetryPolicy(slept *[]time.Duration) RetryPolicy {
+	p := DefaultRetryPolicy
+	p.Jitter = 0
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return nil
+	}
+	return p
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := DefaultRetryPolicy
+	p.Jitter = 0
+	assert.Equal(t, 1*time.Second, p.backoff(0))
+	assert.Equal(t, 4*time.Second, p.backoff(2))
+	assert.Equal(t, 32*time.Second, p.backoff(10))
+	p.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := p.backoff(1)
+		assert.True(t, 1*time.Second <= d && d <= 3*time.Second)
+	}
+}
+
+func TestGetWorksheet_Retry(t *testing.T) {
+	var slept []time.Duration
+	client, m := newDummyClient(
+		dummyErrorResponse{StatusCode: http.StatusServiceUnavailable},
+		dummyErrorResponse{StatusCode: http.StatusTooManyRequests},
+		map[string]interface{}{
+			"range":          "'シート1'!A1:B2",
+			"majorDimension": "ROWS",
+			"values": []interface{}{
+				[]interface{}{"column1", "column2"},
+				[]interface{}{"1", "2"},
+			},
+		},
+	)
+	ss, err := NewSpreadsheet(client, Retry(newDummyRetryPolicy(&slept)))
+	if err != nil {
+		t.Error(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 3, len(m.req))
+	assert.Equal(t, []time.Duration{1 * time.Second, 2 * time.Second}, slept)
+	assert.Equal(t, "2", ws.Rows[0]["column2"])
+}
+
+func TestGetWorksheet_RetryGiveUp(t *testing.T) {
+	var slept []time.Duration
+	client, m := newDummyClient(
+		dummyErrorResponse{StatusCode: http.StatusServiceUnavailable},
+		dummyErrorResponse{StatusCode: http.StatusServiceUnavailable},
+		dummyErrorResponse{StatusCode: http.StatusNotFound},
+	)
+	p := newDummyRetryPolicy(&slept)
+	p.MaxAttempts = 2
+	ss, err := NewSpreadsheet(client, Retry(p))
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.Error(t, err)
+	assert.Equal(t, 2, len(m.req))
+
+	p.MaxAttempts = 5
+	client, m = newDummyClient(
+		dummyErrorResponse{StatusCode: http.StatusNotFound},
+	)
+	ss, err = NewSpreadsheet(client, Retry(p))
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = ss.GetWorksheet("XXXXXX", "シート1")
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(m.req))
+}
+
+func TestSheetDelete_RetryOnlyRateLimited(t *testing.T) {
+	sheetsResponse := map[string]interface{}{
+		"sheets": []map[string]interface{}{
+			map[string]interface{}{
+				"properties": map[string]interface{}{
+					"sheetId": 1234,
+					"title":   "シート1",
+				},
+			},
+		},
+	}
+	var slept []time.Duration
+	client, m := newDummyClient(
+		sheetsResponse,
+		dummyErrorResponse{StatusCode: http.StatusTooManyRequests},
+		dummyErrorResponse{StatusCode: http.StatusInternalServerError},
+	)
+	ss, err := NewSpreadsheet(client, Retry(newDummyRetryPolicy(&slept)))
+	if err != nil {
+		t.Error(err)
+	}
+	err = ss.SheetDelete("XXXXXX", "シート1")
+	assert.Error(t, err)
+	assert.Equal(t, 3, len(m.req))
+	assert.Equal(t, 1, len(slept))
+}
+
+func TestWorksheetAppend_RetryLanded(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	var slept []time.Duration
+	p := newDummyRetryPolicy(&slept)
+	ws.caller.retry = &p
+	client, m := newDummyClient(
+		dummyErrorResponse{StatusCode: http.StatusBadGateway},
+		map[string]interface{}{
+			"range":          "'シート1'!A5:E5",
+			"majorDimension": "ROWS",
+			"values": []interface{}{
+				[]interface{}{"", "11", "", "14", "17"},
+			},
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.Append([]map[string]string{
+		map[string]string{
+			"column1": "11",
+			"column2": "14",
+			"column3": "17",
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 2, len(m.req))
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s/values/%s!A5:E", "XXXXXX", "シート1"), m.req[1].URL.Path)
+	assert.Equal(t, 4, len(ws.Rows))
+}
+
+func TestWorksheetAppend_RetryNotLanded(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	var slept []time.Duration
+	p := newDummyRetryPolicy(&slept)
+	ws.caller.retry = &p
+	client, m := newDummyClient(
+		dummyErrorResponse{StatusCode: http.StatusBadGateway},
+		map[string]interface{}{
+			"range":          "'シート1'!A5:E5",
+			"majorDimension": "ROWS",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	err = ws.Append([]map[string]string{
+		map[string]string{
+			"column1": "11",
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 3, len(m.req))
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s/values/%s!A5:append", "XXXXXX", "シート1"), m.req[2].URL.Path)
+	assert.Equal(t, 4, len(ws.Rows))
+}
+
+// landedTransport applies the first append and then fails it with 503.
+type landedTransport struct {
+	s    *gsstest.Server
+	done bool
+}
+
+func (t *landedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.s.RoundTrip(req)
+	if err != nil || t.done || !strings.HasSuffix(req.URL.Path, ":append") {
+		return res, err
+	}
+	t.done = true
+	res.Body.Close()
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"error":{"code":503,"message":"unavailable"}}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestWorksheetAppend_RetryLandedBelowOthers(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "v"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	ss, err := NewSpreadsheet(&http.Client{Transport: &landedTransport{s: s}}, Retry(newDummyRetryPolicy(&slept)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ows, err := other.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ows.Append([]map[string]string{{"id": "other"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ws.Append([]map[string]string{{"id": "new"}}); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"other"},
+		[]interface{}{"new"},
+	}, vals)
+	assert.Equal(t, 1, len(slept))
+	// the rows of the other writer are padded in blank
+	assert.Equal(t, [][]string{
+		[]string{"", ""},
+		[]string{"new", ""},
+	}, ws.Values())
+}
@@ -0,0 +1,332 @@
+package gss
+
+import (
+	"context"
+	"strings"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+// Color is an RGB color, each component ranges from 0 to 1.
+type Color struct {
+	Red   float64
+	Green float64
+	Blue  float64
+}
+
+// SheetInfo describes one sheet (tab) of a spreadsheet. TabColor is nil
+// when the tab has no color.
+type SheetInfo struct {
+	Id            int64
+	Title         string
+	Index         int
+	RowCount      int
+	ColumnCount   int
+	Hidden        bool
+	TabColor      *Color
+	FrozenRows    int
+	FrozenColumns int
+}
+
+type SpreadsheetInfo struct {
+	Key      string
+	Title    string
+	Locale   string
+	TimeZone string
+	Sheets   []SheetInfo
+}
+
+func fetchSpreadsheet(ctx context.Context, service *sheets.Service, c caller, key string) (*sheets.Spreadsheet, error) {
+	var r *sheets.Spreadsheet
+	err := c.read(ctx, func() (err error) {
+		r, err = service.Spreadsheets.Get(key).Context(ctx).Do()
+		return err
+	})
+	return r, err
+}
+
+func newSheetInfo(p *sheets.SheetProperties) SheetInfo {
+	res := SheetInfo{
+		Id:     p.SheetId,
+		Title:  p.Title,
+		Index:  int(p.Index),
+		Hidden: p.Hidden,
+	}
+	if g := p.GridProperties; g != nil {
+		res.RowCount = int(g.RowCount)
+		res.ColumnCount = int(g.ColumnCount)
+		res.FrozenRows = int(g.FrozenRowCount)
+		res.FrozenColumns = int(g.FrozenColumnCount)
+	}
+	if c := p.TabColor; c != nil {
+		res.TabColor = &Color{Red: c.Red, Green: c.Green, Blue: c.Blue}
+	}
+	return res
+}
+
+// Sheets lists the sheets of the spreadsheet key in tab order.
+func (ss *Spreadsheet) Sheets(key string) ([]SheetInfo, error) {
+	return ss.SheetsContext(context.Background(), key)
+}
+
+func (ss *Spreadsheet) SheetsContext(ctx context.Context, key string) ([]SheetInfo, error) {
+	info, err := ss.InfoContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return info.Sheets, nil
+}
+
+func (ss *Spreadsheet) Info(key string) (*SpreadsheetInfo, error) {
+	return ss.InfoContext(context.Background(), key)
+}
+
+func (ss *Spreadsheet) InfoContext(ctx context.Context, key string) (*SpreadsheetInfo, error) {
+	r, err := fetchSpreadsheet(ctx, ss.service, ss.caller, key)
+	if err != nil {
+		return nil, err
+	}
+	res := &SpreadsheetInfo{
+		Key:    r.SpreadsheetId,
+		Sheets: make([]SheetInfo, 0, len(r.Sheets)),
+	}
+	if res.Key == "" {
+		res.Key = key
+	}
+	if p := r.Properties; p != nil {
+		res.Title = p.Title
+		res.Locale = p.Locale
+		res.TimeZone = p.TimeZone
+	}
+	ids := make(map[string]int64, len(r.Sheets))
+	for _, s := range r.Sheets {
+		if s.Properties != nil {
+			res.Sheets = append(res.Sheets, newSheetInfo(s.Properties))
+			ids[s.Properties.Title] = s.Properties.SheetId
+		}
+	}
+	ss.sheetIds.store(key, ids)
+	return res, nil
+}
+
+// SheetAdd adds an empty sheet at the end of the spreadsheet, or where
+// SheetIndex puts it.
+func (ss *Spreadsheet) SheetAdd(key, name string, opts ...SheetOption) (SheetInfo, error) {
+	return ss.SheetAddContext(context.Background(), key, name, opts...)
+}
+
+func (ss *Spreadsheet) SheetAddContext(ctx context.Context, key, name string, opts ...SheetOption) (SheetInfo, error) {
+	props := &sheets.SheetProperties{Title: name}
+	for _, opt := range opts {
+		opt(props)
+	}
+	var (
+		r   *sheets.BatchUpdateSpreadsheetResponse
+		req = &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				&sheets.Request{
+					AddSheet: &sheets.AddSheetRequest{
+						Properties: props,
+					},
+				},
+			},
+		}
+	)
+	err := ss.caller.writeOnce(ctx, func() (err error) {
+		r, err = ss.service.Spreadsheets.BatchUpdate(key, req).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return SheetInfo{}, err
+	}
+	ss.sheetIds.apply(key, req.Requests, r, nil)
+	if len(r.Replies) <= 0 || r.Replies[0].AddSheet == nil {
+		return SheetInfo{}, nil
+	}
+	return newSheetInfo(r.Replies[0].AddSheet.Properties), nil
+}
+
+// updateSheetProperties sets the fields of props on the sheet name.
+func (ss *Spreadsheet) updateSheetProperties(ctx context.Context, key, name string, props *sheets.SheetProperties, fields ...string) error {
+	sheetId, err := ss.sheetId(ctx, key, name)
+	if err != nil {
+		return err
+	}
+	props.SheetId = sheetId
+	return ss.updateSheetPropertiesById(ctx, key, props, fields...)
+}
+
+func (ss *Spreadsheet) updateSheetPropertiesById(ctx context.Context, key string, props *sheets.SheetProperties, fields ...string) error {
+	err := ss.caller.write(ctx, func() error {
+		_, err := ss.service.Spreadsheets.BatchUpdate(key, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				&sheets.Request{
+					UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+						Properties: props,
+						Fields:     strings.Join(fields, ","),
+					},
+				},
+			},
+		}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		ss.sheetIds.invalidate(key, err)
+	}
+	return err
+}
+
+func (ss *Spreadsheet) SheetRename(key, name, newName string) error {
+	return ss.SheetRenameContext(context.Background(), key, name, newName)
+}
+
+func (ss *Spreadsheet) SheetRenameContext(ctx context.Context, key, name, newName string) error {
+	err := ss.updateSheetProperties(ctx, key, name, &sheets.SheetProperties{Title: newName}, "title")
+	if err != nil {
+		return err
+	}
+	ss.sheetIds.rename(key, name, newName)
+	return nil
+}
+
+// SheetMove moves the sheet name so that it ends up at index in the tab
+// order.
+func (ss *Spreadsheet) SheetMove(key, name string, index int) error {
+	return ss.SheetMoveContext(context.Background(), key, name, index)
+}
+
+func (ss *Spreadsheet) SheetMoveContext(ctx context.Context, key, name string, index int) error {
+	info, err := ss.InfoContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	for _, s := range info.Sheets {
+		if s.Title != name {
+			continue
+		}
+		// the API counts the target index before the sheet is taken out
+		if s.Index < index {
+			index++
+		}
+		props := &sheets.SheetProperties{
+			Index:           int64(index),
+			ForceSendFields: []string{"Index"},
+		}
+		return ss.updateSheetProperties(ctx, key, name, props, "index")
+	}
+	return sheetNotFound(key, name)
+}
+
+func (ss *Spreadsheet) SheetHide(key, name string) error {
+	return ss.SheetHideContext(context.Background(), key, name)
+}
+
+func (ss *Spreadsheet) SheetHideContext(ctx context.Context, key, name string) error {
+	return ss.updateSheetProperties(ctx, key, name, &sheets.SheetProperties{Hidden: true}, "hidden")
+}
+
+func (ss *Spreadsheet) SheetUnhide(key, name string) error {
+	return ss.SheetUnhideContext(context.Background(), key, name)
+}
+
+func (ss *Spreadsheet) SheetUnhideContext(ctx context.Context, key, name string) error {
+	props := &sheets.SheetProperties{
+		Hidden:          false,
+		ForceSendFields: []string{"Hidden"},
+	}
+	return ss.updateSheetProperties(ctx, key, name, props, "hidden")
+}
+
+// SheetSetTabColor sets the tab color of the sheet name, nil removes it.
+func (ss *Spreadsheet) SheetSetTabColor(key, name string, c *Color) error {
+	return ss.SheetSetTabColorContext(context.Background(), key, name, c)
+}
+
+func (ss *Spreadsheet) SheetSetTabColorContext(ctx context.Context, key, name string, c *Color) error {
+	props := &sheets.SheetProperties{}
+	if c != nil {
+		props.TabColor = c.color()
+	}
+	return ss.updateSheetProperties(ctx, key, name, props, "tabColor")
+}
+
+func (c *Color) color() *sheets.Color {
+	return &sheets.Color{
+		Red:             c.Red,
+		Green:           c.Green,
+		Blue:            c.Blue,
+		ForceSendFields: []string{"Red", "Green", "Blue"},
+	}
+}
+
+// Create makes a new spreadsheet holding sheets, or a single default sheet
+// when none are given, and returns its key.
+func (ss *Spreadsheet) Create(title string, sheetNames ...string) (string, error) {
+	return ss.CreateContext(context.Background(), title, sheetNames...)
+}
+
+func (ss *Spreadsheet) CreateContext(ctx context.Context, title string, sheetNames ...string) (string, error) {
+	req := &sheets.Spreadsheet{
+		Properties: &sheets.SpreadsheetProperties{Title: title},
+	}
+	for _, name := range sheetNames {
+		req.Sheets = append(req.Sheets, &sheets.Sheet{
+			Properties: &sheets.SheetProperties{Title: name},
+		})
+	}
+	var r *sheets.Spreadsheet
+	err := ss.caller.writeOnce(ctx, func() (err error) {
+		r, err = ss.service.Spreadsheets.Create(req).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	ids := make(map[string]int64, len(r.Sheets))
+	for _, s := range r.Sheets {
+		if s.Properties != nil {
+			ids[s.Properties.Title] = s.Properties.SheetId
+		}
+	}
+	ss.sheetIds.store(r.SpreadsheetId, ids)
+	return r.SpreadsheetId, nil
+}
+
+// SheetCopyTo copies the sheet srcName of srcKey to the end of dstKey and
+// renames it to dstName. An empty dstName keeps the name the API gives,
+// "Copy of <srcName>". When the rename fails the copy is left under that
+// name.
+func (ss *Spreadsheet) SheetCopyTo(srcKey, srcName, dstKey, dstName string) error {
+	return ss.SheetCopyToContext(context.Background(), srcKey, srcName, dstKey, dstName)
+}
+
+func (ss *Spreadsheet) SheetCopyToContext(ctx context.Context, srcKey, srcName, dstKey, dstName string) error {
+	sheetId, err := ss.sheetId(ctx, srcKey, srcName)
+	if err != nil {
+		return err
+	}
+	var r *sheets.SheetProperties
+	err = ss.caller.writeOnce(ctx, func() (err error) {
+		r, err = ss.service.Spreadsheets.Sheets.CopyTo(srcKey, sheetId, &sheets.CopySheetToAnotherSpreadsheetRequest{
+			DestinationSpreadsheetId: dstKey,
+		}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		ss.sheetIds.invalidate(srcKey, err)
+		return err
+	}
+	ss.sheetIds.set(dstKey, r.Title, r.SheetId)
+	if dstName == "" || dstName == r.Title {
+		return nil
+	}
+	props := &sheets.SheetProperties{
+		SheetId: r.SheetId,
+		Title:   dstName,
+	}
+	if err := ss.updateSheetPropertiesById(ctx, dstKey, props, "title"); err != nil {
+		return err
+	}
+	ss.sheetIds.rename(dstKey, r.Title, dstName)
+	return nil
+}
@@ -0,0 +1,230 @@
+package gss
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpreadsheetInfo(t *testing.T) {
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+			"properties": map[string]interface{}{
+				"title":    "book",
+				"locale":   "ja_JP",
+				"timeZone": "Asia/Tokyo",
+			},
+			"sheets": []interface{}{
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 0,
+						"title":   "シート1",
+						"index":   0,
+						"gridProperties": map[string]interface{}{
+							"rowCount":       1000,
+							"columnCount":    26,
+							"frozenRowCount": 1,
+						},
+					},
+				},
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 123,
+						"title":   "シート2",
+						"index":   1,
+						"hidden":  true,
+						"tabColor": map[string]interface{}{
+							"red":  1,
+							"blue": 0.5,
+						},
+						"gridProperties": map[string]interface{}{
+							"rowCount":          10,
+							"columnCount":       5,
+							"frozenColumnCount": 2,
+						},
+					},
+				},
+			},
+		},
+	)
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	info, err := ss.Info("XXXXXX")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "/v4/spreadsheets/XXXXXX", m.req[0].URL.Path)
+	assert.Equal(t, &SpreadsheetInfo{
+		Key:      "XXXXXX",
+		Title:    "book",
+		Locale:   "ja_JP",
+		TimeZone: "Asia/Tokyo",
+		Sheets: []SheetInfo{
+			SheetInfo{
+				Id:          0,
+				Title:       "シート1",
+				Index:       0,
+				RowCount:    1000,
+				ColumnCount: 26,
+				FrozenRows:  1,
+			},
+			SheetInfo{
+				Id:            123,
+				Title:         "シート2",
+				Index:         1,
+				RowCount:      10,
+				ColumnCount:   5,
+				Hidden:        true,
+				TabColor:      &Color{Red: 1, Blue: 0.5},
+				FrozenColumns: 2,
+			},
+		},
+	}, info)
+}
+
+// countTransport counts the requests per method.
+type countTransport struct {
+	http.RoundTripper
+	count map[string]int
+}
+
+func (t *countTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count[req.Method]++
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func TestSpreadsheetSheetOps(t *testing.T) {
+	s := gsstest.NewServer()
+	if _, err := s.AddSheet("XXXXXX", "シート1", nil); err != nil {
+		t.Fatal(err)
+	}
+	tr := &countTransport{RoundTripper: s, count: map[string]int{}}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := ss.SheetAdd("XXXXXX", "a", SheetIndex(0), SheetGridSize(10, 5), SheetFrozen(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SheetInfo{
+		Id:          1,
+		Title:       "a",
+		Index:       0,
+		RowCount:    10,
+		ColumnCount: 5,
+		FrozenRows:  1,
+	}, info)
+	_, err = ss.SheetAdd("XXXXXX", "b", SheetHidden(), SheetTabColor(Color{Red: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ss.SheetAdd("XXXXXX", "a")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a", "シート1", "b"}, s.SheetTitles("XXXXXX"))
+
+	if err := ss.SheetRename("XXXXXX", "a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetUnhide("XXXXXX", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetHide("XXXXXX", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetSetTabColor("XXXXXX", "b", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetSetTabColor("XXXXXX", "c", &Color{Green: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	// sheet IDs are fetched once and kept up to date by the calls above
+	assert.Equal(t, 1, tr.count["GET"])
+	assert.True(t, errors.Is(ss.SheetRename("XXXXXX", "a", "d"), ErrSheetNotFound))
+	assert.Equal(t, 2, tr.count["GET"])
+
+	if err := ss.SheetMove("XXXXXX", "c", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetMove("XXXXXX", "b", 0); err != nil {
+		t.Fatal(err)
+	}
+	sheets, err := ss.Sheets("XXXXXX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []SheetInfo{
+		SheetInfo{Id: 2, Title: "b", Index: 0, RowCount: 1000, ColumnCount: 26},
+		SheetInfo{Id: 0, Title: "シート1", Index: 1, RowCount: 1000, ColumnCount: 26},
+		SheetInfo{
+			Id:          1,
+			Title:       "c",
+			Index:       2,
+			RowCount:    10,
+			ColumnCount: 5,
+			Hidden:      true,
+			TabColor:    &Color{Green: 0.5},
+			FrozenRows:  1,
+		},
+	}, sheets)
+}
+
+func TestSpreadsheetCreate_SheetCopyTo(t *testing.T) {
+	s := gsstest.NewServer()
+	ss, err := NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := ss.Create("template", "summary", "detail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"summary", "detail"}, s.SheetTitles(tmpl))
+	_, err = s.AddSheet(tmpl, "data", [][]interface{}{
+		[]interface{}{"name", "total"},
+		[]interface{}{"a", 1.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ss.Create("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, tmpl, report)
+	assert.Equal(t, []string{"Sheet1"}, s.SheetTitles(report))
+	info, err := ss.Info(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "report", info.Title)
+
+	if err := ss.SheetCopyTo(tmpl, "data", report, "customer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetCopyTo(tmpl, "data", report, ""); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"Sheet1", "customer", "Copy of data"}, s.SheetTitles(report))
+	assert.True(t, errors.Is(ss.SheetCopyTo(tmpl, "unknown", report, "x"), ErrSheetNotFound))
+
+	ws, err := ss.GetWorksheet(report, "customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]string{
+		map[string]string{"name": "a", "total": "1"},
+	}, ws.Rows)
+	// the renamed copy is resolved from the cache
+	if err := ss.SheetHide(report, "customer"); err != nil {
+		t.Fatal(err)
+	}
+}
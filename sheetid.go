@@ -0,0 +1,189 @@
+package gss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	sheets "google.golang.org/api/sheets/v4"
+)
+
+// sheetIdCache maps the sheet titles of each spreadsheet key to their IDs.
+// It is filled from Get responses and the replies of the requests that add,
+// copy, rename or delete sheets, and is shared by a Spreadsheet and the
+// Worksheets it returns. A nil or disabled cache keeps nothing.
+type sheetIdCache struct {
+	mu       sync.Mutex
+	disabled bool
+	ids      map[string]map[string]int64
+}
+
+func newSheetIdCache() *sheetIdCache {
+	return &sheetIdCache{ids: map[string]map[string]int64{}}
+}
+
+func (c *sheetIdCache) enabled() bool {
+	return c != nil && !c.disabled
+}
+
+// get returns a copy of the cached map of key.
+func (c *sheetIdCache) get(key string) (map[string]int64, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids, ok := c.ids[key]
+	if !ok {
+		return nil, false
+	}
+	res := make(map[string]int64, len(ids))
+	for k, v := range ids {
+		res[k] = v
+	}
+	return res, true
+}
+
+func (c *sheetIdCache) lookup(key, name string) (int64, bool) {
+	if !c.enabled() {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.ids[key][name]
+	return id, ok
+}
+
+func (c *sheetIdCache) store(key string, ids map[string]int64) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := make(map[string]int64, len(ids))
+	for k, v := range ids {
+		m[k] = v
+	}
+	c.ids[key] = m
+}
+
+// set records a sheet of key, only when the rest of key is cached too.
+func (c *sheetIdCache) set(key, name string, id int64) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ids, ok := c.ids[key]; ok {
+		ids[name] = id
+	}
+}
+
+func (c *sheetIdCache) remove(key, name string) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids[key], name)
+}
+
+func (c *sheetIdCache) rename(key, name, newName string) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id, ok := c.ids[key][name]; ok {
+		delete(c.ids[key], name)
+		c.ids[key][newName] = id
+	}
+}
+
+func (c *sheetIdCache) forget(key string) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, key)
+}
+
+// invalidate forgets key when err may come from a stale sheet ID: the API
+// answers requests naming an unknown sheet ID with 400 and unknown
+// spreadsheets with 404.
+func (c *sheetIdCache) invalidate(key string, err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusBadRequest || apiErr.Code == http.StatusNotFound) {
+		c.forget(key)
+	}
+}
+
+// apply updates the cache of key with the replies of a BatchUpdate.
+func (c *sheetIdCache) apply(key string, requests []*sheets.Request, r *sheets.BatchUpdateSpreadsheetResponse, deleted map[int64]string) {
+	for i, req := range requests {
+		var reply *sheets.Response
+		if i < len(r.Replies) {
+			reply = r.Replies[i]
+		}
+		switch {
+		case req.DeleteSheet != nil:
+			c.remove(key, deleted[req.DeleteSheet.SheetId])
+		case req.DuplicateSheet != nil, req.AddSheet != nil:
+			var props *sheets.SheetProperties
+			if reply != nil && reply.DuplicateSheet != nil {
+				props = reply.DuplicateSheet.Properties
+			} else if reply != nil && reply.AddSheet != nil {
+				props = reply.AddSheet.Properties
+			}
+			if props == nil {
+				c.forget(key)
+				return
+			}
+			c.set(key, props.Title, props.SheetId)
+		}
+	}
+}
+
+// sheetIdMap returns the title to sheet ID map of key, it is fetched only
+// when it is not cached yet.
+func (ss *Spreadsheet) sheetIdMap(ctx context.Context, key string) (map[string]int64, error) {
+	if ids, ok := ss.sheetIds.get(key); ok {
+		return ids, nil
+	}
+	return ss.freshSheetIdMap(ctx, key)
+}
+
+// freshSheetIdMap fetches the map of key and caches it, for requests that
+// need the current number of sheets, which the cache does not follow when
+// other clients add or delete sheets.
+func (ss *Spreadsheet) freshSheetIdMap(ctx context.Context, key string) (map[string]int64, error) {
+	ids, err := fetchSheetIdMap(ctx, ss.service, ss.caller, key)
+	if err != nil {
+		return nil, err
+	}
+	ss.sheetIds.store(key, ids)
+	return ids, nil
+}
+
+// sheetId resolves name, fetching the sheets again when it is not in the
+// cache since another client may have added it.
+func (ss *Spreadsheet) sheetId(ctx context.Context, key, name string) (int64, error) {
+	return lookupSheetId(ctx, ss.service, ss.caller, ss.sheetIds, key, name)
+}
+
+func lookupSheetId(ctx context.Context, service *sheets.Service, c caller, cache *sheetIdCache, key, name string) (int64, error) {
+	if id, ok := cache.lookup(key, name); ok {
+		return id, nil
+	}
+	ids, err := fetchSheetIdMap(ctx, service, c, key)
+	if err != nil {
+		return 0, err
+	}
+	cache.store(key, ids)
+	if id, ok := ids[name]; ok {
+		return id, nil
+	}
+	return 0, sheetNotFound(key, name)
+}
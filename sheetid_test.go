@@ -0,0 +1,132 @@
+package gss
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mix3/go-gss/gsstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCountingSpreadsheet(t *testing.T, s *gsstest.Server, opts ...SpreadsheetOption) (*Spreadsheet, *countTransport) {
+	tr := &countTransport{RoundTripper: s, count: map[string]int{}}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ss, tr
+}
+
+func TestSheetIdCache(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"column1"},
+		[]interface{}{"1"},
+		[]interface{}{"2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, tr := newCountingSpreadsheet(t, s)
+	if err := ss.SheetCopy("XXXXXX", "シート1", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	// the ID of the copy comes from the reply
+	if err := ss.SheetDelete("XXXXXX", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, tr.count["GET"])
+
+	b := ss.Batch("XXXXXX")
+	b.SheetCopy("シート1", "a")
+	b.SheetCopy("シート1", "b")
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	b.SheetDelete("a")
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetRename("XXXXXX", "b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	// copies read the number of sheets afresh
+	assert.Equal(t, 2, tr.count["GET"])
+	assert.Equal(t, []string{"シート1", "c"}, s.SheetTitles("XXXXXX"))
+
+	// worksheets share the cache
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.DeleteRows(0); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, tr.count["GET"])
+
+	// another client recreates the sheet under a new ID
+	other, _ := newCountingSpreadsheet(t, s)
+	if err := other.SheetDelete("XXXXXX", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.SheetAdd("XXXXXX", "c"); err != nil {
+		t.Fatal(err)
+	}
+	err = ss.SheetHide("XXXXXX", "c")
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+	}
+	if err := ss.SheetHide("XXXXXX", "c"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, tr.count["GET"])
+}
+
+func TestSheetIdCache_CopyIndex(t *testing.T) {
+	s := gsstest.NewServer()
+	if _, err := s.AddSheet("XXXXXX", "シート1", nil); err != nil {
+		t.Fatal(err)
+	}
+	ss, _ := newCountingSpreadsheet(t, s)
+	if err := ss.SheetCopy("XXXXXX", "シート1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	// sheets added by another client are not in the cache
+	other, _ := newCountingSpreadsheet(t, s)
+	if _, err := other.SheetAdd("XXXXXX", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetCopy("XXXXXX", "シート1", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.SheetAdd("XXXXXX", "y"); err != nil {
+		t.Fatal(err)
+	}
+	b := ss.Batch("XXXXXX")
+	b.SheetCopy("シート1", "c")
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"シート1", "a", "x", "b", "y", "c"}, s.SheetTitles("XXXXXX"))
+}
+
+func TestSheetIdCache_Disabled(t *testing.T) {
+	s := gsstest.NewServer()
+	if _, err := s.AddSheet("XXXXXX", "シート1", nil); err != nil {
+		t.Fatal(err)
+	}
+	ss, tr := newCountingSpreadsheet(t, s, NoSheetIdCache())
+	if err := ss.SheetCopy("XXXXXX", "シート1", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetHide("XXXXXX", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SheetDelete("XXXXXX", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, tr.count["GET"])
+}
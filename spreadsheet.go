@@ -1,40 +1,69 @@
 package gss
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	sheets "google.golang.org/api/sheets/v4"
 )
 
+const DefaultMaxBatchCells = 10000
+
 type Spreadsheet struct {
-	service *sheets.Service
+	service  *sheets.Service
+	caller   caller
+	sheetIds *sheetIdCache
 }
 
-func NewSpreadsheet(client *http.Client) (*Spreadsheet, error) {
+func NewSpreadsheet(client *http.Client, opts ...SpreadsheetOption) (*Spreadsheet, error) {
 	service, err := sheets.New(client)
 	if err != nil {
 		return nil, err
 	}
-	return &Spreadsheet{service: service}, nil
+	ss := &Spreadsheet{
+		service:  service,
+		sheetIds: newSheetIdCache(),
+	}
+	for _, opt := range opts {
+		opt(ss)
+	}
+	return ss, nil
+}
+
+func (ss *Spreadsheet) GetWorksheet(key, sheetName string, opts ...WorksheetOption) (*Worksheet, error) {
+	return ss.GetWorksheetContext(context.Background(), key, sheetName, opts...)
 }
 
-func (ss *Spreadsheet) GetWorksheet(key, sheetName string) (*Worksheet, error) {
+func (ss *Spreadsheet) GetWorksheetContext(ctx context.Context, key, sheetName string, opts ...WorksheetOption) (*Worksheet, error) {
 	ws := &Worksheet{
 		service:          ss.service,
+		caller:           ss.caller,
+		sheetIds:         ss.sheetIds,
 		sheetKey:         key,
 		sheetName:        sheetName,
+		headerRows:       1,
+		headerSeparator:  ".",
 		MajorDimension:   "ROWS",
 		ValueInputOption: "USER_ENTERED",
+		MaxBatchCells:    DefaultMaxBatchCells,
 	}
-	if err := ws.Refresh(); err != nil {
+	for _, opt := range opts {
+		opt(ws)
+	}
+	if err := ws.RefreshContext(ctx); err != nil {
 		return nil, err
 	}
 	return ws, nil
 }
 
-func (ss *Spreadsheet) sheetIdMap(key string) (map[string]int64, error) {
-	r, err := ss.service.Spreadsheets.Get(key).Do()
+func fetchSheetIdMap(ctx context.Context, service *sheets.Service, c caller, key string) (map[string]int64, error) {
+	r, err := fetchSpreadsheet(ctx, service, c, key)
 	if err != nil {
 		return nil, err
 	}
@@ -46,65 +75,106 @@ func (ss *Spreadsheet) sheetIdMap(key string) (map[string]int64, error) {
 }
 
 func (ss *Spreadsheet) SheetCopy(key, srcName, dstName string) error {
-	sheetIdMap, err := ss.sheetIdMap(key)
+	return ss.SheetCopyContext(context.Background(), key, srcName, dstName)
+}
+
+func (ss *Spreadsheet) SheetCopyContext(ctx context.Context, key, srcName, dstName string) error {
+	// the copy goes after the last sheet, which the cache may be wrong about
+	sheetIdMap, err := ss.freshSheetIdMap(ctx, key)
 	if err != nil {
 		return err
 	}
 	sheetId, ok := sheetIdMap[srcName]
 	if !ok {
-		return fmt.Errorf("sheet_id not found. key:%s name:%s", key, srcName)
-	}
-	_, err = ss.service.Spreadsheets.BatchUpdate(key, &sheets.BatchUpdateSpreadsheetRequest{
-		Requests: []*sheets.Request{
-			&sheets.Request{
-				DuplicateSheet: &sheets.DuplicateSheetRequest{
-					NewSheetName:     dstName,
-					SourceSheetId:    sheetId,
-					InsertSheetIndex: int64(len(sheetIdMap)),
+		return sheetNotFound(key, srcName)
+	}
+	var (
+		r   *sheets.BatchUpdateSpreadsheetResponse
+		req = &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				&sheets.Request{
+					DuplicateSheet: &sheets.DuplicateSheetRequest{
+						NewSheetName:     dstName,
+						SourceSheetId:    sheetId,
+						InsertSheetIndex: int64(len(sheetIdMap)),
+					},
 				},
 			},
-		},
-	}).Do()
+		}
+	)
+	err = ss.caller.writeOnce(ctx, func() (err error) {
+		r, err = ss.service.Spreadsheets.BatchUpdate(key, req).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
+		ss.sheetIds.invalidate(key, err)
 		return err
 	}
+	ss.sheetIds.apply(key, req.Requests, r, nil)
 	return nil
 }
 
 func (ss *Spreadsheet) SheetDelete(key, name string) error {
-	sheetIdMap, err := ss.sheetIdMap(key)
+	return ss.SheetDeleteContext(context.Background(), key, name)
+}
+
+func (ss *Spreadsheet) SheetDeleteContext(ctx context.Context, key, name string) error {
+	sheetId, err := ss.sheetId(ctx, key, name)
 	if err != nil {
 		return err
 	}
-	sheetId, ok := sheetIdMap[name]
-	if !ok {
-		return fmt.Errorf("sheet_id not found. key:%s name:%s", key, name)
-	}
-	_, err = ss.service.Spreadsheets.BatchUpdate(key, &sheets.BatchUpdateSpreadsheetRequest{
-		Requests: []*sheets.Request{
-			&sheets.Request{
-				DeleteSheet: &sheets.DeleteSheetRequest{
-					SheetId: sheetId,
+	err = ss.caller.writeOnce(ctx, func() error {
+		_, err := ss.service.Spreadsheets.BatchUpdate(key, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				&sheets.Request{
+					DeleteSheet: &sheets.DeleteSheetRequest{
+						SheetId: sheetId,
+					},
 				},
 			},
-		},
-	}).Do()
+		}).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
+		ss.sheetIds.invalidate(key, err)
 		return err
 	}
+	ss.sheetIds.remove(key, name)
 	return nil
 }
 
 type Worksheet struct {
 	service          *sheets.Service
+	caller           caller
+	sheetIds         *sheetIdCache
 	sheetKey         string
 	sheetName        string
-	values           [][]string
+	values           [][]Cell
 	headers          []string
 	headerIndexes    []int
+	headerRow        int
+	headerRows       int
+	headerSeparator  string
+	suffixDuplicates bool
+	headerOnly       bool
+	columns          []string
+	cols             int
+	sheetId          int64
+	hasSheetId       bool
+	origRows         []map[string]string
+	key              string
+	keyIndex         map[string]int
+	pending          map[cellKey]Cell
 	Rows             []map[string]string
 	MajorDimension   string
 	ValueInputOption string
+	// ValueRenderOption and DateTimeRenderOption are passed to the API
+	// when reading, e.g. "UNFORMATTED_VALUE" to read numbers as numbers or
+	// "FORMULA" to read formulas instead of their computed values.
+	ValueRenderOption    string
+	DateTimeRenderOption string
+	ConflictPolicy       ConflictPolicy
+	MaxBatchCells        int
 }
 
 func (ws *Worksheet) SheetKey() string {
@@ -115,6 +185,29 @@ func (ws *Worksheet) SheetName() string {
 	return ws.sheetName
 }
 
+func (ws *Worksheet) getSheetId(ctx context.Context) (int64, error) {
+	if ws.hasSheetId {
+		return ws.sheetId, nil
+	}
+	sheetId, err := lookupSheetId(ctx, ws.service, ws.caller, ws.sheetIds, ws.sheetKey, ws.sheetName)
+	if err != nil {
+		return 0, err
+	}
+	ws.sheetId = sheetId
+	ws.hasSheetId = true
+	return sheetId, nil
+}
+
+// invalidateSheetId drops the sheet ID after err, which may come from it
+// being stale.
+func (ws *Worksheet) invalidateSheetId(err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest {
+		ws.hasSheetId = false
+	}
+	ws.sheetIds.invalidate(ws.sheetKey, err)
+}
+
 func (ws *Worksheet) Headers() []string {
 	headers := []string{}
 	for _, v := range ws.headers {
@@ -125,156 +218,826 @@ func (ws *Worksheet) Headers() []string {
 	return headers
 }
 
+func (ws *Worksheet) getValues(ctx context.Context, rng string) (*sheets.ValueRange, error) {
+	call := ws.service.Spreadsheets.Values.Get(ws.sheetKey, rng)
+	if ws.ValueRenderOption != "" {
+		call = call.ValueRenderOption(ws.ValueRenderOption)
+	}
+	if ws.DateTimeRenderOption != "" {
+		call = call.DateTimeRenderOption(ws.DateTimeRenderOption)
+	}
+	var r *sheets.ValueRange
+	err := ws.caller.read(ctx, func() (err error) {
+		r, err = call.Context(ctx).Do()
+		return err
+	})
+	return r, err
+}
+
 func (ws *Worksheet) Refresh() error {
-	r, err := ws.service.Spreadsheets.Values.Get(ws.sheetKey, ws.sheetName).Do()
+	return ws.RefreshContext(context.Background())
+}
+
+func (ws *Worksheet) RefreshContext(ctx context.Context) error {
+	r, err := ws.fetch(ctx)
 	if err != nil {
 		return err
 	}
-	if len(r.Values) <= 0 {
-		return fmt.Errorf("no header. key:%s sheetName:%s", ws.sheetKey, ws.sheetName)
+	return ws.load(r)
+}
+
+// fetch reads what load expects: the whole sheet, or only the header rows
+// and the columns selected with the Columns option placed at their
+// positions.
+func (ws *Worksheet) fetch(ctx context.Context) (*sheets.ValueRange, error) {
+	if err := ws.checkHeaderRows(); err != nil {
+		return nil, err
 	}
-	var (
-		cols          = len(r.Values[0])
-		headers       = make([]string, 0, cols)
-		headerIndexes = make([]int, 0, cols)
-		values        = make([][]string, 0, len(r.Values)-1)
-	)
-	for i, v := range r.Values[0] {
-		if v != "" {
-			headers = append(headers, v.(string))
-			headerIndexes = append(headerIndexes, i)
-		}
+	if !ws.headerOnly && len(ws.columns) <= 0 {
+		return ws.getValues(ctx, ws.sheetName)
+	}
+	headerEnd := ws.headerRow + ws.headerRows
+	r, err := ws.getValues(ctx, fmt.Sprintf("%s!1:%d", ws.sheetName, headerEnd))
+	if err != nil || ws.headerOnly {
+		return r, err
 	}
-	for _, vals := range r.Values[1:] {
-		value := make([]string, cols)
-		for i, v := range vals {
-			if i == cols {
-				break
+	headers, headerIndexes, width, err := ws.parseHeaders(r.Values)
+	if err != nil {
+		return nil, err
+	}
+	_, cols, err := ws.project(headers, headerIndexes)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]string, len(cols))
+	for k, c := range cols {
+		ranges[k] = fmt.Sprintf("%s!%s%d:%s", ws.sheetName, n2c(c+1), ws.dataRow(), n2c(c+1))
+	}
+	vrs, err := ws.batchGetValues(ctx, ranges)
+	if err != nil {
+		return nil, err
+	}
+	values := r.Values
+	for k, c := range cols {
+		for i, vals := range vrs[k].Values {
+			if len(vals) <= 0 {
+				continue
+			}
+			for len(values) <= headerEnd+i {
+				values = append(values, []interface{}{})
 			}
-			value[i] = v.(string)
+			row := values[headerEnd+i]
+			for len(row) <= c {
+				row = append(row, "")
+			}
+			row[c] = vals[0]
+			values[headerEnd+i] = row
+		}
+	}
+	// the sheet may go on below the last row the columns have a value in,
+	// pad the snapshot down to its last row like Append does
+	tail, err := ws.getValues(ctx, fmt.Sprintf("%s!A%d:%s", ws.sheetName, len(values)+1, n2c(width)))
+	if err != nil {
+		return nil, err
+	}
+	for range tail.Values {
+		values = append(values, []interface{}{})
+	}
+	r.Values = values
+	return r, nil
+}
+
+// load replaces the snapshot and ws.Rows with r, as read by fetch.
+func (ws *Worksheet) load(r *sheets.ValueRange) error {
+	first := ws.dataRow() - 1
+	headers, headerIndexes, cols, err := ws.parseHeaders(r.Values)
+	if err != nil {
+		return err
+	}
+	if headers, headerIndexes, err = ws.project(headers, headerIndexes); err != nil {
+		return err
+	}
+	values := make([][]Cell, 0, len(r.Values))
+	if first < len(r.Values) {
+		for _, vals := range r.Values[first:] {
+			values = append(values, ws.newCells(vals, cols))
 		}
-		values = append(values, value)
 	}
 	ws.values = values
 	ws.headers = headers
 	ws.headerIndexes = headerIndexes
+	ws.cols = cols
 	ws.DiscardChanges()
 	return nil
 }
 
-func (ws *Worksheet) Append(rows []map[string]string) error {
+// dataRow returns the A1 row number of the first data row.
+func (ws *Worksheet) dataRow() int {
+	return ws.headerRow + ws.headerRows + 1
+}
+
+func stringCells(rows []map[string]string) []map[string]Cell {
+	res := make([]map[string]Cell, len(rows))
+	for i, row := range rows {
+		res[i] = make(map[string]Cell, len(row))
+		for k, v := range row {
+			if v != "" {
+				res[i][k] = StringCell(v)
+			}
+		}
+	}
+	return res
+}
+
+func (ws *Worksheet) rowValues(rows []map[string]Cell) ([][]interface{}, [][]Cell) {
 	var (
 		v    = make([][]interface{}, len(rows))
-		tmps = make([][]string, len(rows))
+		tmps = make([][]Cell, len(rows))
 	)
 	for i, row := range rows {
-		t := make([]interface{}, len(ws.values[0]))
-		u := make([]string, len(ws.values[0]))
+		t := make([]interface{}, ws.cols)
+		u := make([]Cell, ws.cols)
 		for j, hi := range ws.headerIndexes {
-			h := ws.headers[j]
-			if h != "" {
-				t[hi] = row[h]
-				u[hi] = row[h]
-			} else {
-				t[hi] = ""
-				u[hi] = ""
-			}
+			c := row[ws.headers[j]]
+			t[hi] = c.value()
+			u[hi] = c
 		}
 		v[i] = t
 		tmps[i] = u
 	}
-	_, err := ws.service.Spreadsheets.Values.Append(
-		ws.sheetKey,
-		fmt.Sprintf("%s!A%d", ws.sheetName, len(ws.values)+2),
-		&sheets.ValueRange{
-			MajorDimension: ws.MajorDimension,
-			Values:         v,
-		},
-	).ValueInputOption(ws.ValueInputOption).Do()
+	return v, tmps
+}
+
+func (ws *Worksheet) Append(rows []map[string]string) error {
+	return ws.AppendCellsContext(context.Background(), stringCells(rows))
+}
+
+func (ws *Worksheet) AppendContext(ctx context.Context, rows []map[string]string) error {
+	return ws.AppendCellsContext(ctx, stringCells(rows))
+}
+
+func (ws *Worksheet) AppendCells(rows []map[string]Cell) error {
+	return ws.AppendCellsContext(context.Background(), rows)
+}
+
+func (ws *Worksheet) AppendCellsContext(ctx context.Context, rows []map[string]Cell) error {
+	if err := ws.rowsLoaded(); err != nil {
+		return err
+	}
+	var (
+		v, tmps = ws.rowValues(rows)
+		r       *sheets.AppendValuesResponse
+		found   = -1
+	)
+	err := ws.caller.run(ctx, WriteCall, func(attempt int) (err error) {
+		if 0 < attempt {
+			i, ok, err := ws.appended(ctx, len(ws.values), tmps)
+			if err != nil {
+				return err
+			}
+			if ok {
+				found = i
+				return nil
+			}
+		}
+		r, err = ws.service.Spreadsheets.Values.Append(
+			ws.sheetKey,
+			fmt.Sprintf("%s!A%d", ws.sheetName, len(ws.values)+ws.dataRow()),
+			&sheets.ValueRange{
+				MajorDimension: ws.MajorDimension,
+				Values:         v,
+			},
+		).ValueInputOption(ws.ValueInputOption).Context(ctx).Do()
+		return err
+	}, ws.caller.retry.retryable)
 	if err != nil {
 		return err
 	}
+	// the API appends after the last row of the table, which may be below
+	// the snapshot when others appended rows since the last Refresh
+	if 0 <= found {
+		for len(ws.values) < found {
+			ws.values = append(ws.values, make([]Cell, ws.cols))
+		}
+	} else if r != nil && r.Updates != nil {
+		if row, ok := a1Row(r.Updates.UpdatedRange); ok {
+			for len(ws.values)+ws.dataRow() < row {
+				ws.values = append(ws.values, make([]Cell, ws.cols))
+			}
+		}
+	}
 	ws.values = append(ws.values, tmps...)
 	ws.DiscardChanges()
 	return nil
 }
 
+// InsertRows inserts rows before ws.Rows[at]. Uncommitted edits of the other
+// rows are kept. When writing the values fails, the rows stay inserted blank
+// on the sheet and hold the values as uncommitted edits in ws.Rows, so that
+// Update writes them.
+func (ws *Worksheet) InsertRows(at int, rows []map[string]string) error {
+	return ws.InsertCellsContext(context.Background(), at, stringCells(rows))
+}
+
+func (ws *Worksheet) InsertRowsContext(ctx context.Context, at int, rows []map[string]string) error {
+	return ws.InsertCellsContext(ctx, at, stringCells(rows))
+}
+
+func (ws *Worksheet) InsertCells(at int, rows []map[string]Cell) error {
+	return ws.InsertCellsContext(context.Background(), at, rows)
+}
+
+func (ws *Worksheet) InsertCellsContext(ctx context.Context, at int, rows []map[string]Cell) error {
+	if err := ws.rowsLoaded(); err != nil {
+		return err
+	}
+	if at < 0 || len(ws.Rows) < at {
+		return rowOutOfRange(at, len(ws.Rows))
+	}
+	if len(rows) <= 0 {
+		return nil
+	}
+	rowIndexes, err := ws.rowIndexes()
+	if err != nil {
+		return err
+	}
+	pos := len(ws.values)
+	if at < len(rowIndexes) {
+		pos = rowIndexes[at]
+	}
+	sheetId, err := ws.getSheetId(ctx)
+	if err != nil {
+		return err
+	}
+	err = ws.caller.writeOnce(ctx, func() error {
+		_, err := ws.service.Spreadsheets.BatchUpdate(ws.sheetKey, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				ws.insertRequest(sheetId, pos, len(rows)),
+			},
+		}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		ws.invalidateSheetId(err)
+		return err
+	}
+	// the rows are on the sheet now, keep the snapshot in line with it even
+	// if writing their values fails
+	v, tmps := ws.rowValues(rows)
+	ws.insertStagedRows(at, pos, tmps)
+	err = ws.caller.write(ctx, func() error {
+		_, err := ws.service.Spreadsheets.Values.Update(
+			ws.sheetKey,
+			fmt.Sprintf("%s!A%d", ws.sheetName, pos+ws.dataRow()),
+			&sheets.ValueRange{
+				MajorDimension: ws.MajorDimension,
+				Values:         v,
+			},
+		).ValueInputOption(ws.ValueInputOption).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	copy(ws.values[pos:], tmps)
+	ws.adoptRows()
+	return nil
+}
+
+func (ws *Worksheet) insertRequest(sheetId int64, pos, n int) *sheets.Request {
+	return &sheets.Request{
+		InsertDimension: &sheets.InsertDimensionRequest{
+			Range: &sheets.DimensionRange{
+				SheetId:    sheetId,
+				Dimension:  "ROWS",
+				StartIndex: int64(pos + ws.dataRow() - 1),
+				EndIndex:   int64(pos + ws.dataRow() - 1 + n),
+			},
+			InheritFromBefore: 0 < pos,
+		},
+	}
+}
+
+// insertRows puts vals into the local snapshot at ws.values[pos] and
+// ws.Rows[at].
+func (ws *Worksheet) insertRows(at, pos int, vals [][]Cell) {
+	newRows := make([]map[string]string, len(vals))
+	for i, v := range vals {
+		newRows[i] = ws.newRow(v)
+	}
+	ws.values = append(ws.values[:pos], append(vals, ws.values[pos:]...)...)
+	ws.origRows = append(ws.origRows[:pos], append(newRows, ws.origRows[pos:]...)...)
+	ws.Rows = append(ws.Rows[:at], append(newRows, ws.Rows[at:]...)...)
+}
+
+// insertStagedRows inserts blank rows into the snapshot and vals into
+// ws.Rows as uncommitted edits, for rows that are on the sheet but whose
+// values may not have been written yet.
+func (ws *Worksheet) insertStagedRows(at, pos int, vals [][]Cell) {
+	blanks := make([][]Cell, len(vals))
+	for i := range blanks {
+		blanks[i] = make([]Cell, ws.cols)
+	}
+	ws.insertRows(at, pos, blanks)
+	if ws.pending == nil {
+		ws.pending = map[cellKey]Cell{}
+	}
+	for i, cells := range vals {
+		row := ws.Rows[at+i]
+		for j, h := range ws.headers {
+			c := cells[ws.headerIndexes[j]]
+			row[h] = c.String()
+			if row[h] != "" {
+				ws.pending[cellKey{row: reflect.ValueOf(row).Pointer(), header: h}] = c
+			}
+		}
+	}
+}
+
+// appended looks for vals on the sheet from ws.values[pos] down to the
+// last row, i.e. whether a failed Append was applied after all, possibly
+// below rows others appended in the meantime. It returns the ws.values index
+// the rows were found at.
+func (ws *Worksheet) appended(ctx context.Context, pos int, vals [][]Cell) (int, bool, error) {
+	blank := true
+	for _, row := range vals {
+		for _, c := range row {
+			if c.String() != "" {
+				blank = false
+			}
+		}
+	}
+	if blank {
+		return 0, false, nil
+	}
+	r, err := ws.getValues(ctx, fmt.Sprintf(
+		"%s!A%d:%s", ws.sheetName, pos+ws.dataRow(), n2c(ws.cols),
+	))
+	if err != nil {
+		return 0, false, err
+	}
+	remote := make([][]Cell, len(r.Values))
+	for i, v := range r.Values {
+		remote[i] = ws.newCells(v, ws.cols)
+	}
+	for i := range remote {
+		if ws.sameRows(remote[i:], vals) {
+			return pos + i, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// sameRows reports whether remote starts with vals, rows missing from remote
+// count as blank.
+func (ws *Worksheet) sameRows(remote, vals [][]Cell) bool {
+	for i, row := range vals {
+		for _, c := range ws.headerIndexes {
+			v := ""
+			if i < len(remote) {
+				v = remote[i][c].String()
+			}
+			if v != row[c].String() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (ws *Worksheet) Values() [][]string {
 	values := make([][]string, len(ws.values))
 	for i, v := range ws.values {
 		values[i] = make([]string, len(v))
-		copy(values[i], v)
+		for j, c := range v {
+			values[i][j] = c.String()
+		}
 	}
 	return values
 }
 
+func (ws *Worksheet) newRow(vals []Cell) map[string]string {
+	row := make(map[string]string, len(vals))
+	for i, headerIndex := range ws.headerIndexes {
+		var (
+			k = ws.headers[i]
+			v = ""
+		)
+		if headerIndex < len(vals) {
+			v = vals[headerIndex].String()
+		}
+		row[k] = v
+	}
+	return row
+}
+
 func (ws *Worksheet) DiscardChanges() {
 	rows := make([]map[string]string, 0, len(ws.values))
 	for _, vals := range ws.values {
-		row := make(map[string]string, len(vals))
-		for i, headerIndex := range ws.headerIndexes {
-			var (
-				k = ws.headers[i]
-				v = ""
-			)
-			if headerIndex < len(vals) {
-				v = vals[headerIndex]
+		rows = append(rows, ws.newRow(vals))
+	}
+	ws.Rows = rows
+	ws.origRows = make([]map[string]string, len(rows))
+	copy(ws.origRows, rows)
+	ws.pending = nil
+}
+
+// rowIndexes maps each entry of ws.Rows to its index in ws.values. Rows are
+// matched by identity, so entries removed from ws.Rows show up as gaps. When
+// that fails but ws.Rows still has one entry per row, e.g. because a row
+// map was replaced or the rows were sorted, they are matched by position.
+// rowIndexes does not modify ws, see adoptRows.
+func (ws *Worksheet) rowIndexes() ([]int, error) {
+	index := make(map[uintptr]int, len(ws.origRows))
+	for i, row := range ws.origRows {
+		index[reflect.ValueOf(row).Pointer()] = i
+	}
+	res := make([]int, len(ws.Rows))
+	prev := -1
+	for i, row := range ws.Rows {
+		j, ok := index[reflect.ValueOf(row).Pointer()]
+		if !ok || j <= prev {
+			if len(ws.Rows) == len(ws.values) {
+				for i := range res {
+					res[i] = i
+				}
+				return res, nil
+			}
+			if !ok {
+				return nil, fmt.Errorf("%w, use Append for new rows. row:%d", ErrUnknownRow, i)
 			}
-			row[k] = v
+			return nil, fmt.Errorf("%w. row:%d", ErrRowsReordered, i)
 		}
-		rows = append(rows, row)
+		res[i] = j
+		prev = j
 	}
-	ws.Rows = rows
+	return res, nil
 }
 
-func (ws *Worksheet) Update() error {
-	type tmp struct {
-		row int
-		col int
-		val string
+// adoptRows makes the entries of ws.Rows the snapshot rows once they were
+// written, so that rows matched by position are matched by identity from
+// then on. It does nothing while ws.Rows has gaps.
+func (ws *Worksheet) adoptRows() {
+	if len(ws.Rows) == len(ws.origRows) {
+		copy(ws.origRows, ws.Rows)
+	}
+}
+
+func (ws *Worksheet) DeleteRows(indexes ...int) error {
+	return ws.DeleteRowsContext(context.Background(), indexes...)
+}
+
+func (ws *Worksheet) DeleteRowsContext(ctx context.Context, indexes ...int) error {
+	if err := ws.rowsLoaded(); err != nil {
+		return err
 	}
+	rowIndexes, err := ws.rowIndexes()
+	if err != nil {
+		return err
+	}
+	var (
+		deleted = ws.removedRows(rowIndexes)
+		remove  = make(map[int]bool, len(indexes))
+	)
+	for _, i := range indexes {
+		if i < 0 || len(ws.Rows) <= i {
+			return rowOutOfRange(i, len(ws.Rows))
+		}
+		if !remove[i] {
+			remove[i] = true
+			deleted = append(deleted, rowIndexes[i])
+		}
+	}
+	if err := ws.deleteRows(ctx, deleted); err != nil {
+		return err
+	}
+	rows := make([]map[string]string, 0, len(ws.Rows)-len(remove))
+	for i, row := range ws.Rows {
+		if !remove[i] {
+			rows = append(rows, row)
+		}
+	}
+	ws.Rows = rows
+	ws.adoptRows()
+	return nil
+}
 
+func (ws *Worksheet) removedRows(rowIndexes []int) []int {
 	var (
-		tmps = []tmp{}
-		data = []*sheets.ValueRange{}
+		res  = []int{}
+		next = 0
 	)
+	for _, j := range append(rowIndexes, len(ws.values)) {
+		for ; next < j; next++ {
+			res = append(res, next)
+		}
+		next = j + 1
+	}
+	return res
+}
+
+// deleteRows removes the given ws.values indexes from the sheet and from the
+// local snapshot.
+func (ws *Worksheet) deleteRows(ctx context.Context, indexes []int) error {
+	if len(indexes) <= 0 {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	sheetId, err := ws.getSheetId(ctx)
+	if err != nil {
+		return err
+	}
+	err = ws.caller.writeOnce(ctx, func() error {
+		_, err := ws.service.Spreadsheets.BatchUpdate(ws.sheetKey, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: ws.deleteRequests(sheetId, indexes),
+		}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		ws.invalidateSheetId(err)
+		return err
+	}
+	ws.removeRows(indexes)
+	return nil
+}
+
+// deleteRequests builds the requests deleting indexes, which must be sorted
+// in descending order, merging consecutive rows.
+func (ws *Worksheet) deleteRequests(sheetId int64, indexes []int) []*sheets.Request {
+	requests := []*sheets.Request{}
+	for i := 0; i < len(indexes); {
+		j := i + 1
+		for j < len(indexes) && indexes[j] == indexes[j-1]-1 {
+			j++
+		}
+		requests = append(requests, &sheets.Request{
+			DeleteDimension: &sheets.DeleteDimensionRequest{
+				Range: &sheets.DimensionRange{
+					SheetId:    sheetId,
+					Dimension:  "ROWS",
+					StartIndex: int64(indexes[j-1] + ws.dataRow() - 1),
+					EndIndex:   int64(indexes[i] + ws.dataRow()),
+				},
+			},
+		})
+		i = j
+	}
+	return requests
+}
+
+func (ws *Worksheet) removeRows(indexes []int) {
+	for _, i := range indexes {
+		ws.values = append(ws.values[:i], ws.values[i+1:]...)
+		ws.origRows = append(ws.origRows[:i], ws.origRows[i+1:]...)
+	}
+}
+
+type change struct {
+	row   int
+	index int
+	col   int
+	val   string
+	cell  Cell
+}
+
+// changes lists the cells of ws.Rows that differ from the snapshot. row is
+// the index in ws.Rows and index the one in ws.values.
+func (ws *Worksheet) changes(rowIndexes []int) []change {
+	res := []change{}
 	for r, row := range ws.Rows {
+		i := rowIndexes[r]
 		for j, k := range ws.headers {
 			c := ws.headerIndexes[j]
-			v := row[k]
-			if v != ws.values[r][c] {
-				tmps = append(tmps, tmp{
-					row: r,
-					col: c,
-					val: row[k],
-				})
-				data = append(data, &sheets.ValueRange{
-					MajorDimension: ws.MajorDimension,
-					Range: fmt.Sprintf(
-						"%s!%s%d:%s%d",
-						ws.sheetName, n2c(c+1), r+2, n2c(c+1), r+2,
-					),
-					Values: [][]interface{}{
-						[]interface{}{v},
-					},
+			if v := row[k]; v != ws.values[i][c].String() {
+				res = append(res, change{
+					row:   r,
+					index: i,
+					col:   c,
+					val:   v,
+					cell:  ws.editedCell(row, k, v),
 				})
 			}
 		}
 	}
-	_, err := ws.service.Spreadsheets.Values.BatchUpdate(
-		ws.sheetKey,
-		&sheets.BatchUpdateValuesRequest{
-			Data:             data,
-			ValueInputOption: ws.ValueInputOption,
-		},
-	).Do()
+	return res
+}
+
+func (ws *Worksheet) Update() error {
+	return ws.UpdateContext(context.Background())
+}
+
+// UpdateContext stops between chunks once ctx is done. Chunks that were
+// already sent are kept in the local snapshot, so a later Update only sends
+// the rest.
+func (ws *Worksheet) UpdateContext(ctx context.Context) error {
+	if err := ws.rowsLoaded(); err != nil {
+		return err
+	}
+	rowIndexes, err := ws.rowIndexes()
 	if err != nil {
 		return err
 	}
-	for _, t := range tmps {
-		ws.values[t.row][t.col] = t.val
+	var (
+		changes     = ws.changes(rowIndexes)
+		conflictErr error
+	)
+	if ws.ConflictPolicy != ConflictOverwrite {
+		changes, err = ws.resolveConflicts(ctx, rowIndexes, changes)
+		if err != nil {
+			if _, ok := err.(*ConflictError); !ok || ws.ConflictPolicy == ConflictFail {
+				return err
+			}
+			conflictErr = err
+		}
 	}
-	return nil
+	if deleted := ws.removedRows(rowIndexes); 0 < len(deleted) {
+		if err := ws.deleteRows(ctx, deleted); err != nil {
+			return err
+		}
+	}
+
+	for _, chunk := range chunkBlocks(coalesce(changes), ws.MaxBatchCells) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data := make([]*sheets.ValueRange, 0, len(chunk))
+		for _, b := range chunk {
+			data = append(data, ws.valueRange(b))
+		}
+		err = ws.caller.write(ctx, func() error {
+			_, err := ws.service.Spreadsheets.Values.BatchUpdate(
+				ws.sheetKey,
+				&sheets.BatchUpdateValuesRequest{
+					Data:             data,
+					ValueInputOption: ws.ValueInputOption,
+				},
+			).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, b := range chunk {
+			for _, t := range b.changes {
+				ws.values[t.row][t.col] = t.cell
+			}
+		}
+	}
+	ws.adoptRows()
+	return conflictErr
+}
+
+// block is a rectangle of changed cells, rows and cols are inclusive.
+type block struct {
+	top     int
+	bottom  int
+	left    int
+	right   int
+	changes []change
+}
+
+func (b block) cells() int {
+	return (b.bottom - b.top + 1) * (b.right - b.left + 1)
+}
+
+// coalesce merges changes, which must be ordered by row and col, into runs
+// of adjacent columns and then stacks runs spanning the same columns on
+// consecutive rows into blocks.
+func coalesce(changes []change) []block {
+	var (
+		res  = []block{}
+		open = map[[2]int]int{}
+	)
+	for i := 0; i < len(changes); {
+		j := i + 1
+		for j < len(changes) && changes[j].row == changes[i].row && changes[j].col == changes[j-1].col+1 {
+			j++
+		}
+		var (
+			row  = changes[i].row
+			cols = [2]int{changes[i].col, changes[j-1].col}
+		)
+		if k, ok := open[cols]; ok && res[k].bottom == row-1 {
+			res[k].bottom = row
+			res[k].changes = append(res[k].changes, changes[i:j]...)
+		} else {
+			open[cols] = len(res)
+			res = append(res, block{
+				top:     row,
+				bottom:  row,
+				left:    cols[0],
+				right:   cols[1],
+				changes: append([]change{}, changes[i:j]...),
+			})
+		}
+		i = j
+	}
+	return res
+}
+
+// chunkBlocks groups blocks into batches of at most max cells, splitting
+// blocks by rows when needed. A max of zero or less means no limit. There
+// is always at least one batch.
+func chunkBlocks(blocks []block, max int) [][]block {
+	var (
+		res   = [][]block{}
+		chunk = []block{}
+		cells = 0
+	)
+	for _, b := range blocks {
+		parts := []block{b}
+		if 0 < max && max < b.cells() {
+			parts = splitBlock(b, max)
+		}
+		for _, p := range parts {
+			if 0 < max && 0 < len(chunk) && max < cells+p.cells() {
+				res = append(res, chunk)
+				chunk, cells = []block{}, 0
+			}
+			chunk = append(chunk, p)
+			cells += p.cells()
+		}
+	}
+	if 0 < len(chunk) || len(res) <= 0 {
+		res = append(res, chunk)
+	}
+	return res
+}
+
+func splitBlock(b block, max int) []block {
+	rows := max / (b.right - b.left + 1)
+	if rows < 1 {
+		rows = 1
+	}
+	res := []block{}
+	for top := b.top; top <= b.bottom; top += rows {
+		p := block{
+			top:    top,
+			bottom: top + rows - 1,
+			left:   b.left,
+			right:  b.right,
+		}
+		if b.bottom < p.bottom {
+			p.bottom = b.bottom
+		}
+		for _, t := range b.changes {
+			if p.top <= t.row && t.row <= p.bottom {
+				p.changes = append(p.changes, t)
+			}
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+func (ws *Worksheet) valueRange(b block) *sheets.ValueRange {
+	var (
+		height = b.bottom - b.top + 1
+		width  = b.right - b.left + 1
+		values [][]interface{}
+	)
+	if ws.MajorDimension == "COLUMNS" {
+		values = make([][]interface{}, width)
+		for i := range values {
+			values[i] = make([]interface{}, height)
+		}
+		for _, t := range b.changes {
+			values[t.col-b.left][t.row-b.top] = t.cell.value()
+		}
+	} else {
+		values = make([][]interface{}, height)
+		for i := range values {
+			values[i] = make([]interface{}, width)
+		}
+		for _, t := range b.changes {
+			values[t.row-b.top][t.col-b.left] = t.cell.value()
+		}
+	}
+	return &sheets.ValueRange{
+		MajorDimension: ws.MajorDimension,
+		Range: fmt.Sprintf(
+			"%s!%s%d:%s%d",
+			ws.sheetName, n2c(b.left+1), b.top+ws.dataRow(), n2c(b.right+1), b.bottom+ws.dataRow(),
+		),
+		Values: values,
+	}
+}
+
+// a1Row returns the row number of the top left cell of an A1 range such as
+// "'Sheet1'!A7:C8".
+func a1Row(rng string) (int, bool) {
+	if i := strings.LastIndex(rng, "!"); 0 <= i {
+		rng = rng[i+1:]
+	}
+	rng = strings.TrimLeft(rng, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	if i := strings.Index(rng, ":"); 0 <= i {
+		rng = rng[:i]
+	}
+	n, err := strconv.Atoi(rng)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
 }
 
 func n2c(i int) string {
@@ -285,10 +1048,10 @@ func n2c(i int) string {
 		j = i % 26
 		i = i / 26
 		if 0 < i {
-			r = fmt.Sprintf("%s%s", string('A'+j), r)
+			r = fmt.Sprintf("%s%s", string(rune('A'+j)), r)
 		} else {
 			break
 		}
 	}
-	return fmt.Sprintf("%s%s", string('A'+j), r)
+	return fmt.Sprintf("%s%s", string(rune('A'+j)), r)
 }
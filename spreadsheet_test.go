@@ -1,14 +1,19 @@
 package gss
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"testing"
 
 	sheets "google.golang.org/api/sheets/v4"
 
+	"github.com/mix3/go-gss/gsstest"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,7 +47,7 @@ func Test_sheetIdMap(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	r, err := ss.sheetIdMap("XXXXXX")
+	r, err := ss.sheetIdMap(context.Background(), "XXXXXX")
 	if err != nil {
 		t.Error(err)
 	}
@@ -607,3 +612,606 @@ func TestN2C(t *testing.T) {
 	assert.Equal(t, "Z", n2c(26))
 	assert.Equal(t, "AA", n2c(27))
 }
+
+func TestWorksheetDeleteRows(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"sheets": []map[string]interface{}{
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 1234,
+						"title":   "シート1",
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"replies":       []interface{}{},
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.Rows[2]["column1"] = "99"
+	err = ws.DeleteRows(0, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{
+				"deleteDimension": map[string]interface{}{
+					"range": map[string]interface{}{
+						"sheetId":    1234.0,
+						"dimension":  "ROWS",
+						"startIndex": 1.0,
+						"endIndex":   3.0,
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s:batchUpdate", "XXXXXX"), m.req[1].URL.Path)
+	assert.Equal(t, [][]string{
+		[]string{"", "3", "", "6", "9"},
+	}, ws.Values())
+	assert.Equal(t, []map[string]string{
+		map[string]string{
+			"column1": "99",
+			"column2": "6",
+			"column3": "9",
+		},
+	}, ws.Rows)
+}
+
+func TestWorksheetUpdate_DeletedRows(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"sheets": []map[string]interface{}{
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 1234,
+						"title":   "シート1",
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"replies":       []interface{}{},
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.Rows = append(ws.Rows[:1], ws.Rows[2:]...)
+	ws.Rows[1]["column2"] = "99"
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{
+				"deleteDimension": map[string]interface{}{
+					"range": map[string]interface{}{
+						"sheetId":    1234.0,
+						"dimension":  "ROWS",
+						"startIndex": 2.0,
+						"endIndex":   3.0,
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	err = json.NewDecoder(m.req[2].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData = map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!D3:D3",
+				"values": []interface{}{
+					[]interface{}{"99"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, [][]string{
+		[]string{"", "1", "", "4", "7"},
+		[]string{"", "3", "", "99", "9"},
+	}, ws.Values())
+
+	ws.Rows = append(ws.Rows, map[string]string{})
+	assert.Error(t, ws.Update())
+}
+
+func TestWorksheetInsertRows(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"sheets": []map[string]interface{}{
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"sheetId": 1234,
+						"title":   "シート1",
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"replies":       []interface{}{},
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+			"updatedRange":  "'シート1'!A3:E3",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.Rows[2]["column3"] = "99"
+	err = ws.InsertRows(1, []map[string]string{
+		map[string]string{
+			"column1": "11",
+			"column2": "14",
+			"column3": "17",
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{
+				"insertDimension": map[string]interface{}{
+					"range": map[string]interface{}{
+						"sheetId":    1234.0,
+						"dimension":  "ROWS",
+						"startIndex": 2.0,
+						"endIndex":   3.0,
+					},
+					"inheritFromBefore": true,
+				},
+			},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	err = json.NewDecoder(m.req[2].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData = map[string]interface{}{
+		"majorDimension": "ROWS",
+		"values": []interface{}{
+			[]interface{}{nil, "11", nil, "14", "17"},
+		},
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s/values/%s!A3", "XXXXXX", "シート1"), m.req[2].URL.Path)
+	assert.Equal(t, url.Values{
+		"alt":              []string{"json"},
+		"valueInputOption": []string{"USER_ENTERED"},
+	}, m.req[2].URL.Query())
+	assert.Equal(t, [][]string{
+		[]string{"", "1", "", "4", "7"},
+		[]string{"", "11", "", "14", "17"},
+		[]string{"", "2", "", "5", "8"},
+		[]string{"", "3", "", "6", "9"},
+	}, ws.Values())
+	assert.Equal(t, "99", ws.Rows[3]["column3"])
+	assert.Equal(t, "11", ws.Rows[1]["column1"])
+	rowIndexes, err := ws.rowIndexes()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3}, rowIndexes)
+}
+
+// failTransport makes the gsstest server fail the requests match accepts
+// with code.
+type failTransport struct {
+	s     *gsstest.Server
+	code  int
+	match func(req *http.Request) bool
+}
+
+func (t *failTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.match != nil && t.match(req) {
+		t.s.FailNext(t.code)
+	}
+	return t.s.RoundTrip(req)
+}
+
+func TestWorksheetInsertRows_ValuesFailed(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"1", "a"},
+		[]interface{}{"2", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &failTransport{s: s, code: http.StatusBadRequest}
+	ss, err := NewSpreadsheet(&http.Client{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.match = func(req *http.Request) bool { return req.Method == http.MethodPut }
+	assert.Error(t, ws.InsertCells(0, []map[string]Cell{{"id": NumberCell(0), "v": StringCell("z")}}))
+	// the row stays inserted blank on the sheet, ws.Rows holds its values
+	assert.Equal(t, []map[string]string{
+		map[string]string{"id": "0", "v": "z"},
+		map[string]string{"id": "1", "v": "a"},
+		map[string]string{"id": "2", "v": "b"},
+	}, ws.Rows)
+
+	tr.match = nil
+	ws.Rows[1]["v"] = "A"
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{0.0, "z"},
+		[]interface{}{"1", "A"},
+		[]interface{}{"2", "b"},
+	}, vals)
+	c, err := ws.GetCell(0, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, NumberCell(0), c)
+}
+
+func TestWorksheetUpdate_Coalesce(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.MaxBatchCells = 4
+	ws.Rows[0]["column2"] = "a"
+	ws.Rows[0]["column3"] = "b"
+	ws.Rows[1]["column2"] = "c"
+	ws.Rows[1]["column3"] = "d"
+	ws.Rows[2]["column1"] = "e"
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 2, len(m.req))
+	var reqData interface{}
+	err = json.NewDecoder(m.req[0].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!D2:E3",
+				"values": []interface{}{
+					[]interface{}{"a", "b"},
+					[]interface{}{"c", "d"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData = map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!B4:B4",
+				"values": []interface{}{
+					[]interface{}{"e"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, [][]string{
+		[]string{"", "1", "", "a", "b"},
+		[]string{"", "2", "", "c", "d"},
+		[]string{"", "e", "", "6", "9"},
+	}, ws.Values())
+}
+
+func TestCoalesce(t *testing.T) {
+	changes := []change{
+		change{row: 0, col: 1, val: "a"},
+		change{row: 0, col: 2, val: "b"},
+		change{row: 1, col: 1, val: "c"},
+		change{row: 1, col: 2, val: "d"},
+		change{row: 1, col: 4, val: "e"},
+		change{row: 3, col: 1, val: "f"},
+		change{row: 3, col: 2, val: "g"},
+	}
+	blocks := coalesce(changes)
+	assert.Equal(t, 3, len(blocks))
+	assert.Equal(t, []int{0, 1, 1, 2}, []int{blocks[0].top, blocks[0].bottom, blocks[0].left, blocks[0].right})
+	assert.Equal(t, []int{1, 1, 4, 4}, []int{blocks[1].top, blocks[1].bottom, blocks[1].left, blocks[1].right})
+	assert.Equal(t, []int{3, 3, 1, 2}, []int{blocks[2].top, blocks[2].bottom, blocks[2].left, blocks[2].right})
+
+	chunks := chunkBlocks(blocks, 2)
+	assert.Equal(t, 4, len(chunks))
+	assert.Equal(t, 1, len(chunks[0]))
+	assert.Equal(t, 0, chunks[0][0].bottom)
+	assert.Equal(t, 2, len(chunks[1][0].changes))
+	assert.Equal(t, 1, len(chunks[1]))
+	assert.Equal(t, 1, len(chunks[2]))
+	assert.Equal(t, 1, len(chunks[3]))
+	assert.Equal(t, [][]block{[]block{}}, chunkBlocks(nil, 2))
+}
+
+func TestGetWorksheet_HeaderRow(t *testing.T) {
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"range":          "'シート1'!A1:C5",
+			"majorDimension": "ROWS",
+			"values": []interface{}{
+				[]interface{}{"title"},
+				[]interface{}{"name", "", "score"},
+				[]interface{}{"first", "last"},
+				[]interface{}{"a", "b", "1"},
+				[]interface{}{"c", "d", "2"},
+			},
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"range":          "'シート1'!A1:A1",
+			"majorDimension": "ROWS",
+			"values": []interface{}{
+				[]interface{}{"title"},
+			},
+		},
+	)
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1", HeaderRow(2), MultiRowHeader(2, "."))
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, []string{"name.first", "name.last", "score"}, ws.Headers())
+	assert.Equal(t, []map[string]string{
+		map[string]string{
+			"name.first": "a",
+			"name.last":  "b",
+			"score":      "1",
+		},
+		map[string]string{
+			"name.first": "c",
+			"name.last":  "d",
+			"score":      "2",
+		},
+	}, ws.Rows)
+
+	ws.Rows[1]["score"] = "3"
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	var reqData map[string]interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "シート1!C5:C5", reqData["data"].([]interface{})[0].(map[string]interface{})["range"])
+
+	err = ws.Append([]map[string]string{
+		map[string]string{"score": "4"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, fmt.Sprintf("/v4/spreadsheets/%s/values/%s!A6:append", "XXXXXX", "シート1"), m.req[2].URL.Path)
+
+	_, err = ss.GetWorksheet("XXXXXX", "シート1", HeaderRow(10))
+	assert.Error(t, err)
+
+	for _, opt := range []WorksheetOption{HeaderRow(0), HeaderRow(-3), MultiRowHeader(0, ".")} {
+		_, err = ss.GetWorksheet("XXXXXX", "シート1", opt)
+		assert.True(t, errors.Is(err, ErrNoHeader))
+	}
+}
+
+type cancelTransport struct {
+	http.RoundTripper
+	cancel context.CancelFunc
+}
+
+func (t *cancelTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.RoundTripper.RoundTrip(req)
+	t.cancel()
+	return res, err
+}
+
+func TestWorksheetUpdateContext_Canceled(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	client.Transport = &cancelTransport{RoundTripper: m, cancel: cancel}
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ws.MaxBatchCells = 1
+	ws.Rows[0]["column2"] = "a"
+	ws.Rows[2]["column1"] = "e"
+	err = ws.UpdateContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, len(m.req))
+	assert.Equal(t, [][]string{
+		[]string{"", "1", "", "a", "7"},
+		[]string{"", "2", "", "5", "8"},
+		[]string{"", "3", "", "6", "9"},
+	}, ws.Values())
+
+	client.Transport = m
+	err = ws.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 2, len(m.req))
+	var reqData interface{}
+	err = json.NewDecoder(m.req[1].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!B4:B4",
+				"values": []interface{}{
+					[]interface{}{"e"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+}
+
+func TestSpreadsheetGetWorksheetContext_Canceled(t *testing.T) {
+	client, m := newDummyClient()
+	ss, err := NewSpreadsheet(client)
+	if err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ss.GetWorksheetContext(ctx, "XXXXXX", "シート1")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, len(m.req))
+}
+
+func TestWorksheetUpdate_ReplacedRows(t *testing.T) {
+	s := gsstest.NewServer()
+	_, err := s.AddSheet("XXXXXX", "シート1", [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"1", "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := NewSpreadsheet(s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws, err := ss.GetWorksheet("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a new map in place of a row is matched by position
+	ws.Rows[0] = map[string]string{"id": "2", "v": "B"}
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	ws.Rows[0]["v"] = "BB"
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(ws.Rows, func(i, j int) bool { return ws.Rows[i]["id"] < ws.Rows[j]["id"] })
+	if err := ws.Update(); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := s.Values("XXXXXX", "シート1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]interface{}{
+		[]interface{}{"id", "v"},
+		[]interface{}{1.0, "a"},
+		[]interface{}{2.0, "BB"},
+	}, vals)
+	assert.Equal(t, [][]string{
+		[]string{"1", "a"},
+		[]string{"2", "BB"},
+	}, ws.Values())
+}
@@ -0,0 +1,210 @@
+package gss
+
+import (
+	"context"
+	"sync"
+)
+
+// SyncWorksheet guards a Worksheet for use by several goroutines. Rows are
+// read and edited through its methods, which copy rows in and out, so no
+// goroutine holds a map another one writes to.
+//
+// Update, Append and the other writes hold the lock until the API calls
+// returned. Refresh only locks while it swaps in the new snapshot, reads
+// are not blocked while it waits for the API.
+type SyncWorksheet struct {
+	mu sync.RWMutex
+	// io serializes the calls that talk to the API.
+	io sync.Mutex
+	ws *Worksheet
+}
+
+// NewSyncWorksheet takes over ws, which must not be used directly anymore.
+func NewSyncWorksheet(ws *Worksheet) *SyncWorksheet {
+	return &SyncWorksheet{ws: ws}
+}
+
+// Do runs fn with exclusive access to the worksheet. ws must not be kept
+// after fn returned.
+func (s *SyncWorksheet) Do(fn func(ws *Worksheet) error) error {
+	s.io.Lock()
+	defer s.io.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.ws)
+}
+
+// View runs fn with read access to the worksheet, fn must not modify it.
+func (s *SyncWorksheet) View(fn func(ws *Worksheet) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.ws)
+}
+
+func (s *SyncWorksheet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ws.Rows)
+}
+
+func (s *SyncWorksheet) Headers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ws.Headers()
+}
+
+// Row returns a copy of ws.Rows[i].
+func (s *SyncWorksheet) Row(i int) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i < 0 || len(s.ws.Rows) <= i {
+		return nil, rowOutOfRange(i, len(s.ws.Rows))
+	}
+	return copyRow(s.ws.Rows[i]), nil
+}
+
+// Rows returns a copy of ws.Rows.
+func (s *SyncWorksheet) Rows() []map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rows := make([]map[string]string, len(s.ws.Rows))
+	for i, row := range s.ws.Rows {
+		rows[i] = copyRow(row)
+	}
+	return rows
+}
+
+func (s *SyncWorksheet) Get(i int, header string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, err := s.ws.cellKey(i, header); err != nil {
+		return "", err
+	}
+	return s.ws.Rows[i][header], nil
+}
+
+// Set edits ws.Rows[i][header], it is sent by the next Update.
+func (s *SyncWorksheet) Set(i int, header, v string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.ws.cellKey(i, header); err != nil {
+		return err
+	}
+	s.ws.Rows[i][header] = v
+	return nil
+}
+
+// SetRow edits the headers of ws.Rows[i] present in row.
+func (s *SyncWorksheet) SetRow(i int, row map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range row {
+		if _, err := s.ws.cellKey(i, k); err != nil {
+			return err
+		}
+	}
+	for k, v := range row {
+		s.ws.Rows[i][k] = v
+	}
+	return nil
+}
+
+func (s *SyncWorksheet) GetCell(i int, header string) (Cell, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ws.GetCell(i, header)
+}
+
+func (s *SyncWorksheet) SetCell(i int, header string, c Cell) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ws.SetCell(i, header, c)
+}
+
+// FindByKey returns the index and a copy of the row whose key column equals
+// value.
+func (s *SyncWorksheet) FindByKey(value string) (int, map[string]string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok, err := s.ws.FindByKey(value)
+	if err != nil || !ok {
+		return 0, nil, ok, err
+	}
+	return s.ws.keyIndex[value], copyRow(row), true, nil
+}
+
+func (s *SyncWorksheet) Values() [][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ws.Values()
+}
+
+func (s *SyncWorksheet) Refresh() error {
+	return s.RefreshContext(context.Background())
+}
+
+func (s *SyncWorksheet) RefreshContext(ctx context.Context) error {
+	s.io.Lock()
+	defer s.io.Unlock()
+	r, err := s.ws.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ws.load(r)
+}
+
+func (s *SyncWorksheet) Update() error {
+	return s.UpdateContext(context.Background())
+}
+
+func (s *SyncWorksheet) UpdateContext(ctx context.Context) error {
+	return s.Do(func(ws *Worksheet) error {
+		return ws.UpdateContext(ctx)
+	})
+}
+
+func (s *SyncWorksheet) Append(rows []map[string]string) error {
+	return s.AppendContext(context.Background(), rows)
+}
+
+func (s *SyncWorksheet) AppendContext(ctx context.Context, rows []map[string]string) error {
+	return s.Do(func(ws *Worksheet) error {
+		return ws.AppendContext(ctx, rows)
+	})
+}
+
+func (s *SyncWorksheet) DeleteRows(indexes ...int) error {
+	return s.DeleteRowsContext(context.Background(), indexes...)
+}
+
+func (s *SyncWorksheet) DeleteRowsContext(ctx context.Context, indexes ...int) error {
+	return s.Do(func(ws *Worksheet) error {
+		return ws.DeleteRowsContext(ctx, indexes...)
+	})
+}
+
+func (s *SyncWorksheet) Upsert(rows []map[string]string) error {
+	return s.UpsertContext(context.Background(), rows)
+}
+
+func (s *SyncWorksheet) UpsertContext(ctx context.Context, rows []map[string]string) error {
+	return s.Do(func(ws *Worksheet) error {
+		return ws.UpsertContext(ctx, rows)
+	})
+}
+
+func (s *SyncWorksheet) DiscardChanges() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ws.DiscardChanges()
+}
+
+func copyRow(row map[string]string) map[string]string {
+	res := make(map[string]string, len(row))
+	for k, v := range row {
+		res[k] = v
+	}
+	return res
+}
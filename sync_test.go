@@ -0,0 +1,155 @@
+package gss
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+
+	sheets "google.golang.org/api/sheets/v4"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncWorksheet(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Error(err)
+	}
+	client, m := newDummyClient(
+		map[string]interface{}{
+			"spreadsheetId": "XXXXXX",
+		},
+		map[string]interface{}{
+			"range":          "'シート1'!A1:E4",
+			"majorDimension": "ROWS",
+			"values": []interface{}{
+				[]interface{}{"", "column1", "", "column2", "column3"},
+				[]interface{}{"", "1", "", "a", "7"},
+				[]interface{}{"", "2", "", "5", "b"},
+			},
+		},
+	)
+	ws.service, err = sheets.New(client)
+	if err != nil {
+		t.Error(err)
+	}
+	s := NewSyncWorksheet(ws)
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, []string{"column1", "column2", "column3"}, s.Headers())
+
+	row, err := s.Row(0)
+	if err != nil {
+		t.Error(err)
+	}
+	row["column2"] = "x"
+	v, err := s.Get(0, "column2")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "4", v)
+	assert.Error(t, s.Set(3, "column2", "a"))
+	assert.Error(t, s.Set(0, "unknown", "a"))
+	assert.Error(t, s.SetRow(0, map[string]string{"column2": "a", "unknown": "b"}))
+	_, err = s.Row(-1)
+	assert.Error(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Rows()
+				s.Values()
+				s.Get(0, "column1")
+			}
+		}()
+	}
+	err = s.Set(0, "column2", "a")
+	if err != nil {
+		t.Error(err)
+	}
+	err = s.SetRow(1, map[string]string{"column3": "b"})
+	if err != nil {
+		t.Error(err)
+	}
+	err = s.Update()
+	if err != nil {
+		t.Error(err)
+	}
+	err = s.Refresh()
+	if err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+
+	var reqData interface{}
+	err = json.NewDecoder(m.req[0].Body).Decode(&reqData)
+	if err != nil {
+		t.Error(err)
+	}
+	expectReqData := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!D2:D2",
+				"values": []interface{}{
+					[]interface{}{"a"},
+				},
+			},
+			map[string]interface{}{
+				"majorDimension": "ROWS",
+				"range":          "シート1!E3:E3",
+				"values": []interface{}{
+					[]interface{}{"b"},
+				},
+			},
+		},
+		"valueInputOption": "USER_ENTERED",
+	}
+	assert.Equal(t, expectReqData, reqData)
+	assert.Equal(t, []map[string]string{
+		map[string]string{"column1": "1", "column2": "a", "column3": "7"},
+		map[string]string{"column1": "2", "column2": "5", "column3": "b"},
+	}, s.Rows())
+
+	err = s.View(func(ws *Worksheet) error {
+		assert.Equal(t, 2, len(ws.Rows))
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSyncWorksheetGetCell_SortedRows(t *testing.T) {
+	ws, err := newDummyWorksheet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSyncWorksheet(ws)
+	err = s.Do(func(ws *Worksheet) error {
+		sort.Slice(ws.Rows, func(i, j int) bool { return ws.Rows[i]["column1"] > ws.Rows[j]["column1"] })
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GetCell only reads, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.GetCell(0, "column1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	c, err := s.GetCell(0, "column1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "3", c.String())
+}
@@ -7,6 +7,10 @@ import (
 	"net/http"
 )
 
+type dummyErrorResponse struct {
+	StatusCode int
+}
+
 type mockTransport struct {
 	index             int
 	dummyJsonResponse []interface{}
@@ -21,7 +25,17 @@ func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		StatusCode: http.StatusOK,
 	}
 	res.Header.Set("Content-Type", "application/json")
-	b, err := json.Marshal(t.dummyJsonResponse[t.index])
+	d := t.dummyJsonResponse[t.index]
+	if e, ok := d.(dummyErrorResponse); ok {
+		res.StatusCode = e.StatusCode
+		d = map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    e.StatusCode,
+				"message": http.StatusText(e.StatusCode),
+			},
+		}
+	}
+	b, err := json.Marshal(d)
 	if err != nil {
 		return nil, err
 	}